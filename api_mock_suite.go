@@ -1,18 +1,96 @@
 package testkit
 
 import (
+	"context"
+	"net/http"
 	"net/url"
 	"os"
 	"path/filepath"
 	"regexp"
 
 	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
 	"github.com/wiremock/go-wiremock"
 	"gopkg.in/yaml.v3"
+
+	"github.com/bdpiprava/testkit/internal"
 )
 
 var testNameSanitizer = regexp.MustCompile(`[^a-zA-Z0-9]+`)
 
+const (
+	apiMockProviderName    = "api-mock"
+	defaultWiremockAddress = "http://localhost:8080"
+)
+
+func init() {
+	RegisterProvider(&apiMockProvider{})
+}
+
+// apiMockProvider provides the namespaces of the wiremock server to the tests
+type apiMockProvider struct {
+	address string
+	client  *wiremock.Client
+}
+
+// apiMockNamespace is a namespace of the wiremock server, the stubs of the namespace are matched under its path
+type apiMockNamespace struct {
+	noopRelease
+	client *wiremock.Client
+	url    string
+}
+
+// Name returns the name of the provider
+func (p *apiMockProvider) Name() string {
+	return apiMockProviderName
+}
+
+// Init initializes the wiremock client from the api-mock section of the config file,
+// the default address is used when the section is missing
+func (p *apiMockProvider) Init(config ConfigSection, _ logrus.FieldLogger) error {
+	cfg := internal.APIMockConfig{Address: defaultWiremockAddress}
+	if err := config.Decode(&cfg); err != nil {
+		return err
+	}
+
+	p.address = cfg.Address
+	p.client = wiremock.NewClient(cfg.Address)
+	return nil
+}
+
+// Acquire returns the namespace requested by the test
+func (p *apiMockProvider) Acquire(_ context.Context, request AcquireRequest) (Resource, error) {
+	mockURL, err := url.JoinPath(p.address, request.Name)
+	if err != nil {
+		return nil, err
+	}
+	return &apiMockNamespace{client: p.client, url: mockURL}, nil
+}
+
+// HealthCheck requests the mappings of the wiremock server
+func (p *apiMockProvider) HealthCheck(ctx context.Context) error {
+	endpoint, err := url.JoinPath(p.address, "__admin", "mappings")
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer closeSilently(res.Body)
+
+	if res.StatusCode != http.StatusOK {
+		return errors.Errorf("wiremock responded with status %d", res.StatusCode)
+	}
+	return nil
+}
+
 // SetupAPIMocksFromFile set up the services mock from a file and return the URLs
 func (s *Suite) SetupAPIMocksFromFile(file string, dynamicParams map[string]string) map[string]string {
 	root, err := readFile(file)
@@ -25,12 +103,13 @@ func (s *Suite) SetupAPIMocksFromFile(file string, dynamicParams map[string]stri
 	serviceURLs := make(map[string]string)
 	for name, paths := range root {
 		testPath := filepath.Join(name, testNameSanitizer.ReplaceAllString(s.T().Name(), "_"))
-		serviceURLs[name], err = url.JoinPath(suiteConfig.APIMockConfig.Address, testPath)
-		s.NoError(err)
+		namespace := s.AcquireResource(apiMockProviderName, testPath, nil).(*apiMockNamespace)
+		s.recordResource("api_mock.namespace", testPath)
+		serviceURLs[name] = namespace.url
 
 		for _, path := range paths {
 			path.Request.Path = filepath.Join(testPath, path.Request.Path)
-			err = wiremockClient.StubFor(path.Request.ToWiremockRequest(dynamicParams).
+			err = namespace.client.StubFor(path.Request.ToWiremockRequest(dynamicParams).
 				WillReturnResponse(path.Response.ToWiremockResponse(dynamicParams)).
 				AtPriority(1))
 
@@ -44,21 +123,22 @@ func (s *Suite) SetupAPIMocksFromFile(file string, dynamicParams map[string]stri
 // SetAPIMock sets the wiremock server with the given method, path, status and body
 func (s *Suite) SetAPIMock(namespace, method, path string, status int, body string) string {
 	namespace = testNameSanitizer.ReplaceAllString(namespace, "_")
+	mock := s.AcquireResource(apiMockProviderName, namespace, nil).(*apiMockNamespace)
+	s.recordResource("api_mock.namespace", namespace)
 	stubRule := wiremock.NewStubRule(method, wiremock.URLMatching(filepath.Join("/", namespace, path))).
 		WillReturnResponse(wiremock.NewResponse().WithStatus(int64(status)).WithBody(body)).
 		AtPriority(1)
 
-	s.Require().NoError(wiremockClient.StubFor(stubRule))
-
-	mockURL, err := url.JoinPath(suiteConfig.APIMockConfig.Address, namespace)
-	s.Require().NoError(err)
-
-	return mockURL
+	s.Require().NoError(mock.client.StubFor(stubRule))
+	return mock.url
 }
 
 // CleanAPIMock resets the wiremock server
 func (s *Suite) CleanAPIMock() {
-	err := wiremockClient.Reset()
+	provider, err := s.providerOf(apiMockProviderName)
+	s.Require().NoError(err)
+
+	err = provider.(*apiMockProvider).client.Reset()
 	s.Require().NoError(err)
 }
 
@@ -0,0 +1,211 @@
+package testkit
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/bdpiprava/testkit/internal"
+)
+
+type (
+	SuiteConfig         = internal.SuiteConfig         // SuiteConfig is the configuration of a suite
+	PostgresConfig      = internal.PostgresConfig      // PostgresConfig is the configuration of the postgres databases
+	ElasticSearchConfig = internal.ElasticSearchConfig // ElasticSearchConfig is the configuration of the elasticsearch and opensearch clients
+	GoMigrateConfig     = internal.GoMigrateConfig     // GoMigrateConfig is the configuration of the go migrate migrations
+	APIMockConfig       = internal.APIMockConfig       // APIMockConfig is the configuration of the API mock
+	KafkaConfig         = internal.KafkaConfig         // KafkaConfig is the configuration of the kafka clusters of the tests
+	KafkaSASLConfig     = internal.KafkaSASLConfig     // KafkaSASLConfig is the SASL authentication to the kafka broker
+	KafkaTLSConfig      = internal.KafkaTLSConfig      // KafkaTLSConfig is the TLS connection to the kafka broker
+	ReadinessConfig     = internal.ReadinessConfig     // ReadinessConfig is the configuration of the wait for the backends to be ready
+	BackoffConfig       = internal.BackoffConfig       // BackoffConfig is the configuration of the wait for a backend to be ready
+)
+
+// configurable is implemented by the suites holding their own config
+type configurable interface {
+	suiteConfig() *internal.SuiteConfig
+	overrideConfig(config SuiteConfig)
+}
+
+// RunWithConfig runs the suite with the given config instead of the config file, the sections of the config file
+// which are not part of SuiteConfig e.g. of the custom providers are still used when the file exists
+func RunWithConfig(t *testing.T, suite TestingSuite, config SuiteConfig) {
+	c, ok := suite.(configurable)
+	if !ok {
+		t.Fatalf("testkit: RunWithConfig requires a suite embedding testkit.Suite, got %T", suite)
+	}
+
+	c.overrideConfig(config)
+	Run(t, suite)
+}
+
+// Config returns a copy of the config of the suite
+func (s *Suite) Config() SuiteConfig {
+	if s.config == nil {
+		return SuiteConfig{}
+	}
+	return s.config.Clone()
+}
+
+func (s *Suite) suiteConfig() *internal.SuiteConfig {
+	return s.config
+}
+
+func (s *Suite) overrideConfig(config SuiteConfig) {
+	override := config.Clone()
+	s.override = &override
+}
+
+// configOf returns the config of the suite, nil if the suite does not hold one
+func configOf(suite TestingSuite) *internal.SuiteConfig {
+	if c, ok := suite.(configurable); ok {
+		return c.suiteConfig()
+	}
+	return nil
+}
+
+// resolveConfig returns the config of the suite and the sections passed to its providers. The config is the config
+// file, or the override of RunWithConfig, changed by ConfigureSuite
+func (s *Suite) resolveConfig() (*internal.SuiteConfig, map[string]any, error) {
+	fileCfg, sections, err := loadFileConfig()
+	if err != nil && (s.override == nil || !errors.Is(err, internal.ErrConfigNotFound)) {
+		return nil, nil, err
+	}
+
+	var config internal.SuiteConfig
+	if s.override != nil {
+		config = s.override.Clone()
+	} else {
+		config = fileCfg.Clone()
+	}
+
+	configurable, ok := s.s.(ConfigurableSuite)
+	if ok {
+		configurable.ConfigureSuite(&config)
+	}
+
+	if s.override == nil && !ok {
+		return &config, sections, nil
+	}
+
+	if err = internal.ApplyDefaults(&config); err != nil {
+		return nil, nil, err
+	}
+
+	if err = internal.ValidateSuiteConfig(config); err != nil {
+		return nil, nil, err
+	}
+
+	sections, err = sectionsOf(config, fileCfg, sections)
+	return &config, sections, err
+}
+
+// sectionsOf returns the sections of the config file with the sections of the config which differ from the config
+// file replaced, the sections which are not set in the config are removed
+func sectionsOf(config internal.SuiteConfig, fileCfg *internal.SuiteConfig, fileSections map[string]any) (map[string]any, error) {
+	values, err := configValues(config)
+	if err != nil {
+		return nil, err
+	}
+
+	var fileValues map[string]any
+	if fileCfg != nil {
+		if fileValues, err = configValues(*fileCfg); err != nil {
+			return nil, err
+		}
+	}
+
+	sections := maps.Clone(fileSections)
+	if sections == nil {
+		sections = make(map[string]any)
+	}
+
+	for key, value := range values {
+		if !reflect.DeepEqual(value, fileValues[key]) {
+			sections[key] = value
+		}
+	}
+
+	for key := range fileValues {
+		if _, ok := values[key]; !ok {
+			delete(sections, key)
+		}
+	}
+	return sections, nil
+}
+
+// configValues returns the values of the config by their yaml key, the sections which are not set are omitted
+func configValues(config internal.SuiteConfig) (map[string]any, error) {
+	content, err := yaml.Marshal(config)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal suite config")
+	}
+
+	var values map[string]any
+	return values, errors.Wrap(yaml.Unmarshal(content, &values), "failed to unmarshal suite config")
+}
+
+// sensitiveKeys are the parts of the keys whose values are masked when the config is printed
+var sensitiveKeys = []string{"password", "secret", "token"}
+
+// MergedConfig is the configuration of the suites, the config file deep merged with its overlays
+type MergedConfig struct {
+	Files   []string          // Files are the config files merged in order, the later ones override the earlier ones
+	Values  map[string]any    // Values are the merged values
+	Sources map[string]string // Sources are the files each value came from by dotted key e.g. postgres.query_params.sslmode
+}
+
+// EffectiveConfig returns the configuration used by the suites: the config file, the files of the profiles listed in
+// TESTKIT_PROFILE, the local file and the TESTKIT_* environment variables deep merged in this order, with the file
+// each value came from, environment for the variables
+func EffectiveConfig() (MergedConfig, error) {
+	effective, err := internal.ReadEffectiveConfig()
+	if err != nil {
+		return MergedConfig{}, err
+	}
+	return MergedConfig(effective), nil
+}
+
+// String returns the merged values one per line with the file they came from, the secrets are masked
+func (c MergedConfig) String() string {
+	var sb strings.Builder
+	sb.WriteString("files: " + strings.Join(c.Files, ", ") + "\n")
+	for _, key := range slices.Sorted(maps.Keys(c.Sources)) {
+		value, _ := c.Lookup(key)
+		if isSensitive(key) {
+			value = "******"
+		}
+		sb.WriteString(fmt.Sprintf("%s = %v (%s)\n", key, value, c.Sources[key]))
+	}
+	return sb.String()
+}
+
+// Lookup returns the merged value of the dotted key e.g. postgres.query_params.sslmode
+func (c MergedConfig) Lookup(key string) (any, bool) {
+	var current any = c.Values
+	for _, part := range strings.Split(key, ".") {
+		values, ok := current.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+
+		if current, ok = values[part]; !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// isSensitive returns true if the value of the key is a secret
+func isSensitive(key string) bool {
+	name := strings.ToLower(key[strings.LastIndex(key, ".")+1:])
+	return slices.ContainsFunc(sensitiveKeys, func(sensitive string) bool {
+		return strings.Contains(name, sensitive)
+	})
+}
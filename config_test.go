@@ -0,0 +1,100 @@
+package testkit_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bdpiprava/testkit"
+)
+
+type ConfigurableSuiteTest struct {
+	testkit.Suite
+	config testkit.SuiteConfig
+}
+
+func TestConfigurableSuiteTest(t *testing.T) {
+	s := new(ConfigurableSuiteTest)
+	testkit.Run(t, s)
+
+	require.Equal(t, 1, s.config.Retries)
+	require.Equal(t, time.Minute, s.config.TestTimeout)
+}
+
+func (s *ConfigurableSuiteTest) ConfigureSuite(config *testkit.SuiteConfig) {
+	config.Retries = 1
+	config.TestTimeout = time.Minute
+}
+
+func (s *ConfigurableSuiteTest) Test_Config() {
+	s.config = s.Config()
+}
+
+type RunWithConfigSuiteTest struct {
+	testkit.Suite
+	config testkit.SuiteConfig
+}
+
+func TestRunWithConfigSuiteTest(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	config := testkit.SuiteConfig{
+		APIMockConfig: &testkit.APIMockConfig{Address: server.URL},
+	}
+
+	s := new(RunWithConfigSuiteTest)
+	testkit.RunWithConfig(t, s, config)
+
+	config.LogLevel = "info"
+	config.Readiness = s.config.Readiness
+	require.Equal(t, config, s.config)
+
+	other := new(RunWithConfigSuiteTest)
+	testkit.Run(t, other)
+
+	require.Zero(t, other.config.Retries)
+	require.NotEqual(t, config, other.config)
+}
+
+func (s *RunWithConfigSuiteTest) Test_Config() {
+	s.config = s.Config()
+}
+
+type OverriddenSectionsSuiteTest struct {
+	testkit.Suite
+	ran bool
+}
+
+func TestOverriddenSectionsSuiteTest(t *testing.T) {
+	var checks atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		checks.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	// the suite does not wait for the backends of the config file whose sections it overrides
+	start := time.Now()
+	s := new(OverriddenSectionsSuiteTest)
+	testkit.RunWithConfig(t, s, testkit.SuiteConfig{
+		APIMockConfig: &testkit.APIMockConfig{Address: server.URL},
+		Readiness: testkit.ReadinessConfig{
+			BackoffConfig: testkit.BackoffConfig{Timeout: 10 * time.Second, Interval: 10 * time.Millisecond},
+		},
+	})
+
+	require.Less(t, time.Since(start), 5*time.Second)
+	require.True(t, s.ran)
+	require.Positive(t, checks.Load())
+}
+
+func (s *OverriddenSectionsSuiteTest) Test_Run() {
+	s.ran = true
+}
@@ -16,11 +16,23 @@ import (
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 
+	"github.com/bdpiprava/testkit/internal"
 	"github.com/bdpiprava/testkit/search"
 )
 
 const successStatusCode = 299
 
+const elasticSearchProviderName = "elasticsearch"
+
+func init() {
+	RegisterProvider(&elasticSearchProvider{})
+}
+
+// elasticSearchProvider provides the elasticsearch client to the tests
+type elasticSearchProvider struct {
+	client *elasticsearch.Client
+}
+
 // SearchClient is the interface for the search client
 type SearchClient interface {
 	// CreateIndex creates a new index
@@ -45,19 +57,76 @@ type SearchClient interface {
 
 // ElasticSearch is a wrapper around the elasticsearch client
 type elasticSearch struct {
+	noopRelease
 	client *elasticsearch.Client
 	log    logrus.FieldLogger
+	ctx    func() context.Context // ctx returns the context of the running test, the client may be shared by the tests
 }
 
 // RequireElasticSearch returns the elasticsearch client
 func (s *Suite) RequireElasticSearch() SearchClient {
-	if esClient == nil {
-		s.T().Fatalf("elasticsearch client is not initialized")
+	if s.config == nil || s.config.ElasticSearch == nil {
+		s.T().Skip("testkit: skipped, elasticsearch is not configured")
+	}
+
+	client := *s.AcquireResource(elasticSearchProviderName, "", nil).(*elasticSearch)
+	client.ctx = s.GetContext
+	return &client
+}
+
+// Name returns the name of the provider
+func (p *elasticSearchProvider) Name() string {
+	return elasticSearchProviderName
+}
+
+// Init initializes the elasticsearch client from the elasticsearch section of the config file
+func (p *elasticSearchProvider) Init(config ConfigSection, _ logrus.FieldLogger) (err error) {
+	if !config.Exists() {
+		return nil
+	}
+
+	var cfg internal.ElasticSearchConfig
+	if err = config.Decode(&cfg); err != nil {
+		return err
 	}
+
+	p.client, err = elasticsearch.NewClient(elasticsearch.Config{
+		Addresses: strings.Split(cfg.Addresses, ","),
+		Username:  cfg.Username,
+		Password:  cfg.Password,
+	})
+
+	return err
+}
+
+// Acquire returns the client bound to the logger of the test
+func (p *elasticSearchProvider) Acquire(_ context.Context, request AcquireRequest) (Resource, error) {
+	if p.client == nil {
+		return nil, errors.New("elasticsearch client is not initialized")
+	}
+
 	return &elasticSearch{
-		client: esClient,
-		log:    s.Logger(),
+		client: p.client,
+		log:    request.Logger,
+	}, nil
+}
+
+// HealthCheck returns an error unless the health of the elasticsearch cluster is yellow or green
+func (p *elasticSearchProvider) HealthCheck(ctx context.Context) error {
+	if p.client == nil {
+		return errors.New("elasticsearch client is not initialized")
+	}
+
+	res, err := p.client.Cluster.Health(p.client.Cluster.Health.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer closeSilently(res.Body)
+
+	if res.IsError() {
+		return errors.Errorf("elasticsearch cluster health failed: %s", res.String())
 	}
+	return checkClusterHealth(res.Body)
 }
 
 // CreateIndex creates a new index
@@ -79,8 +148,7 @@ func (s *elasticSearch) CreateIndex(index string, settings search.CreateIndexSet
 	}
 
 	log.Debug("executing create index request")
-	ctx := context.Background()
-	resp, err := req.Do(ctx, s.client)
+	resp, err := req.Do(s.ctx(), s.client)
 	if err != nil {
 		log.Debug("failed to execute create index request")
 		return err
@@ -101,7 +169,7 @@ func (s *elasticSearch) CloseIndices(indices ...string) {
 		"indices": indices,
 	})
 	log.Debug("closing indices")
-	resp, err := s.client.Indices.Close(indices)
+	resp, err := s.client.Indices.Close(indices, s.client.Indices.Close.WithContext(s.ctx()))
 	if err != nil {
 		log.Debug("failed to close indices")
 		return
@@ -142,7 +210,7 @@ func (s *elasticSearch) FindIndices(pattern string) (search.Indices, error) {
 
 	log.Debug("finding indices")
 	resp, err := s.client.Cat.Indices(
-		s.client.Cat.Indices.WithContext(context.Background()),
+		s.client.Cat.Indices.WithContext(s.ctx()),
 		s.client.Cat.Indices.WithIndex(pattern),
 		s.client.Cat.Indices.WithFormat("json"),
 	)
@@ -170,6 +238,7 @@ func (s *elasticSearch) GetIndexSettings(index string) (search.IndexSetting, err
 
 	log.Debug("getting index settings")
 	resp, err := s.client.Indices.GetSettings(
+		s.client.Indices.GetSettings.WithContext(s.ctx()),
 		s.client.Indices.GetSettings.WithIndex(index),
 	)
 	if err != nil {
@@ -209,7 +278,7 @@ func (s *elasticSearch) DeleteIndices(pattern string) error {
 	}
 
 	log.Debugf("deleting indices: %v", list)
-	_, err = s.client.Indices.Delete(list)
+	_, err = s.client.Indices.Delete(list, s.client.Indices.Delete.WithContext(s.ctx()))
 	return err
 }
 
@@ -221,7 +290,7 @@ func (s *elasticSearch) DeleteByQuery(indices []string, query string) error {
 	})
 
 	log.Debug("deleting by query")
-	resp, err := s.client.DeleteByQuery(indices, strings.NewReader(query))
+	resp, err := s.client.DeleteByQuery(indices, strings.NewReader(query), s.client.DeleteByQuery.WithContext(s.ctx()))
 	if err != nil {
 		log.Debug("failed to delete by query")
 		return errors.Wrapf(err, "failed to delete by query")
@@ -248,6 +317,7 @@ func (s *elasticSearch) SearchByQuery(index string, query string) (search.QueryR
 	log.Debug("searching by query")
 	var result search.QueryResponse
 	resp, err := s.client.Search(
+		s.client.Search.WithContext(s.ctx()),
 		s.client.Search.WithIndex(index),
 		s.client.Search.WithBody(strings.NewReader(query)),
 	)
@@ -284,6 +354,7 @@ func (s *elasticSearch) CreateDocument(index, docID string, document map[string]
 	resp, err := s.client.Index(
 		index,
 		bytes.NewReader(content),
+		s.client.Index.WithContext(s.ctx()),
 		s.client.Index.WithRefresh("true"),
 		s.client.Index.WithDocumentID(docID),
 	)
@@ -3,9 +3,13 @@ package testkit
 import (
 	"context"
 	"testing"
+	"time"
 
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/require"
+
+	"github.com/bdpiprava/testkit/kitkafka"
 )
 
 // TestingSuite can store and return the current *testing.T context
@@ -30,11 +34,64 @@ type TestingSuite interface {
 	Logger() logrus.FieldLogger
 }
 
+// SubTestRunner is a TestingSuite which can run subtests, it is implemented by Suite
+type SubTestRunner interface {
+	TestingSuite
+
+	// Run runs the subtest function as a subtest of the current test
+	Run(name string, subtest func()) bool
+}
+
+// KafkaSuite is a TestingSuite with the kafka helpers used by the typed helpers e.g. ProduceTyped, it is implemented
+// by Suite
+type KafkaSuite interface {
+	TestingSuite
+
+	// ProduceMessage produces the message to the kafka cluster of the test
+	ProduceMessage(message kitkafka.Message)
+
+	// WaitForMessage waits for a message to be consumed from the kafka topic
+	WaitForMessage(topic string, timeout time.Duration) (*kafka.Message, error)
+
+	// SchemaRegistry returns a client of the schema registry of the test
+	SchemaRegistry() *kitkafka.SchemaRegistryClient
+}
+
 // OnlyOnce has a Do method, which will run only once.
 type OnlyOnce interface {
 	DoOnce(*testing.T) error
 }
 
+// ParallelSuite has a Parallel method, when it returns true the test methods of the suite run in parallel.
+// Each test method runs against its own copy of the suite with isolated T, assertions, context, logger and resources.
+type ParallelSuite interface {
+	Parallel() bool
+}
+
+// TestTimeout has a Timeout method, which returns the maximum duration of each test in the suite.
+// It takes precedence over the test_timeout from the config file, zero disables the timeout.
+type TestTimeout interface {
+	Timeout() time.Duration
+}
+
+// RetryPolicy has a Retries method, which returns the number of times a failed test method is retried by method name.
+// It takes precedence over the -testkit.retries flag and the retries from the config file.
+type RetryPolicy interface {
+	Retries() map[string]int
+}
+
+// Tags has a Tags method, which returns the tags of the test methods by method name. The tests are selected with the
+// -testkit.tags flag and the tests tagged with a TagRequires* tag are skipped when the backend is not configured.
+type Tags interface {
+	Tags() map[string][]string
+}
+
+// ConfigurableSuite has a ConfigureSuite method, which can change the config of the suite read from the config file,
+// or given to RunWithConfig, before the suite starts. The changes are validated and only apply to the suite.
+type ConfigurableSuite interface {
+	ConfigureSuite(config *SuiteConfig)
+}
+
 // SetupSuite has a SetupSuite method, which will run before the tests in the suite are run.
 type SetupSuite interface {
 	SetupSuite()
@@ -55,3 +112,31 @@ type TearDownSuite interface {
 type TearDownTest interface {
 	TearDownTest()
 }
+
+// SetupSubTest has a SetupSubTest method, which will run before each subtest run by Suite.Run.
+// Suites without it run SetupTest before each subtest instead.
+type SetupSubTest interface {
+	SetupSubTest()
+}
+
+// TearDownSubTest has a TearDownSubTest method, which will run after each subtest run by Suite.Run.
+// Suites without it run TearDownTest after each subtest instead.
+type TearDownSubTest interface {
+	TearDownSubTest()
+}
+
+// BeforeTest has a function to be executed right before the test starts and receives the suite and test names as input
+type BeforeTest interface {
+	BeforeTest(suiteName, testName string)
+}
+
+// AfterTest has a function to be executed right after the test finishes and receives the suite and test names as input
+type AfterTest interface {
+	AfterTest(suiteName, testName string)
+}
+
+// WithStats implements HandleStats, a function that will be executed when the suite finished
+// with the timing and status of each test method and subtest
+type WithStats interface {
+	HandleStats(suiteName string, stats *SuiteInformation)
+}
@@ -1,7 +1,6 @@
 package internal
 
 import (
-	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -16,12 +15,15 @@ var configFileMatcher = regexp.MustCompile(`(?i).testkit.config.(yaml|yml)$`)
 const (
 	EnvConfigLocation     = "TESTKIT_CONFIG_LOCATION"
 	EnvDisableConfigCache = "DISABLE_CONFIG_CACHE"
+	EnvProfile            = "TESTKIT_PROFILE"
+	EnvConfigMode         = "TESTKIT_CONFIG_MODE"
 )
 
 // cache is a struct to hold the content of the file and the path
 type cache struct {
 	content      []byte
 	path         string
+	overlays     []configLayer // overlays are the profile and local files merged on top of the file
 	loadedViaEnv bool
 	err          error
 }
@@ -31,14 +33,14 @@ var configCache *cache
 // ReadConfigAs reads the config file and unmarshal it into the given type
 func ReadConfigAs[T any]() (T, error) {
 	var config T
-	content, err := ReadConfigFile()
+	content, files, err := readConfig()
 	if err != nil {
 		return config, err
 	}
 
 	err = yaml.Unmarshal(content, &config)
 	if err != nil {
-		return config, errors.Wrapf(err, "failed to unmarshal config from file %s", configCache.path)
+		return config, errors.Wrapf(err, "failed to unmarshal config from %s", strings.Join(files, ", "))
 	}
 
 	return config, nil
@@ -47,17 +49,59 @@ func ReadConfigAs[T any]() (T, error) {
 // ReadConfigFile read the config file as byte array
 // 1. read the file from environment variable TESTKIT_CONFIG_LOCATION, if set
 // 2. read the file from the current working directory
+// The ${ENV_VAR:-default} references and file:// secret references of the string values are resolved, the
+// profile and local overlays of the file and the TESTKIT_* environment variables are deep merged on top of it
 func ReadConfigFile() ([]byte, error) {
+	content, _, err := readConfig()
+	return content, err
+}
+
+// ReadEffectiveConfig reads the config file merged with its overlays and the file each value came from
+func ReadEffectiveConfig() (EffectiveConfig, error) {
+	layers, err := readLayers()
+	if err != nil {
+		return EffectiveConfig{}, err
+	}
+	return mergeConfigLayers(layers)
+}
+
+// readConfig returns the merged content of the config layers and their paths, the content of a single layer is
+// returned as is when it does not reference any variable or secret file
+func readConfig() ([]byte, []string, error) {
+	layers, err := readLayers()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(layers) == 1 {
+		interpolated, err := interpolate(layers[0].content)
+		if err != nil {
+			return nil, nil, errors.Wrapf(err, "failed to interpolate config file %s", layers[0].path)
+		}
+		return interpolated, []string{layers[0].path}, nil
+	}
+
+	effective, err := mergeConfigLayers(layers)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	content, err := yaml.Marshal(effective.Values)
+	return content, effective.Files, err
+}
+
+// loadConfigFiles reads the config file and its overlays as is, the content is cached unless the cache is disabled
+func loadConfigFiles() (*cache, error) {
 	_, cacheDisabled := os.LookupEnv(EnvDisableConfigCache)
 	// If the config file is already loaded, return the content
 	if !cacheDisabled && configCache != nil && len(configCache.content) > 0 {
-		return configCache.content, configCache.err
+		return configCache, configCache.err
 	}
 
 	location := strings.TrimSpace(os.Getenv(EnvConfigLocation))
 	if location != "" {
 		configCache = readConfigFile(location, true)
-		return configCache.content, configCache.err
+		return configCache, configCache.err
 	}
 
 	wd, err := os.Getwd()
@@ -73,7 +117,7 @@ func ReadConfigFile() ([]byte, error) {
 	}
 
 	configCache = readConfigFile(location, false)
-	return configCache.content, configCache.err
+	return configCache, configCache.err
 }
 
 // locateConfigFile finds the config file in the current directory or its parent
@@ -107,18 +151,23 @@ func locateConfigFile(workingDir string) (string, error) {
 		currentDir = parent
 	}
 
-	return "", fmt.Errorf("config file not found")
+	return "", ErrConfigNotFound
 }
 
-// readConfigFile reads the content of the file and returns the cache struct
+// readConfigFile reads the content of the file and its overlays and returns the cache struct
 func readConfigFile(path string, loadedViaEnv bool) *cache {
 	content, err := os.ReadFile(path)
-	return &cache{
+	result := &cache{
 		content:      content,
 		err:          err,
 		path:         path,
 		loadedViaEnv: loadedViaEnv,
 	}
+
+	if err == nil {
+		result.overlays, result.err = readOverlays(path)
+	}
+	return result
 }
 
 // containsConfigFile returns file name if the directory contains a config file
@@ -0,0 +1,128 @@
+package internal
+
+import (
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	envPrefix = "TESTKIT_"
+	// envSource is the source of the values read from the environment variables
+	envSource = "environment"
+	// ConfigModeEnv reads the config from the environment variables only, the config files are ignored
+	ConfigModeEnv = "env"
+)
+
+// ErrConfigNotFound is returned when neither the config file nor the TESTKIT_* environment variables are found
+var ErrConfigNotFound = errors.New("config file not found")
+
+// readLayers returns the config file with its overlays followed by the environment layer. The config file is
+// optional when the TESTKIT_* environment variables are set, and ignored when TESTKIT_CONFIG_MODE is env
+func readLayers() ([]configLayer, error) {
+	env, err := envLayer()
+	if err != nil {
+		return nil, err
+	}
+
+	if strings.TrimSpace(os.Getenv(EnvConfigMode)) == ConfigModeEnv {
+		if env == nil {
+			return nil, errors.Errorf("%s is %s but no %s* environment variable is set", EnvConfigMode, ConfigModeEnv, envPrefix)
+		}
+		return []configLayer{*env}, nil
+	}
+
+	var layers []configLayer
+	loaded, err := loadConfigFiles()
+	switch {
+	case err == nil:
+		layers = loaded.layers()
+	case errors.Is(err, ErrConfigNotFound) && env != nil:
+	default:
+		return nil, err
+	}
+
+	if env != nil {
+		layers = append(layers, *env)
+	}
+	return layers, nil
+}
+
+// envLayer returns the values of the TESTKIT_* environment variables as a config layer, nil if none is set
+func envLayer() (*configLayer, error) {
+	values := envValues(reflect.TypeOf(SuiteConfig{}), envPrefix)
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	content, err := yaml.Marshal(values)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal config from environment variables")
+	}
+	return &configLayer{path: envSource, content: content}, nil
+}
+
+// envValues returns the values of the fields of the struct type set by the environment variables. The name of the
+// variable is the prefix followed by the yaml name of the field in upper case with - replaced by _, e.g.
+// TESTKIT_API_MOCK_ADDRESS for api-mock.address and TESTKIT_POSTGRES_QUERY_PARAMS_SSLMODE for
+// postgres.query_params.sslmode. The maps of strings are read by key, the other maps e.g. readiness.backends can not be
+// set by the environment variables
+func envValues(typ reflect.Type, prefix string) map[string]any {
+	values := make(map[string]any)
+	for name, field := range yamlFields(typ) {
+		variable := prefix + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+
+		switch fieldType.Kind() {
+		case reflect.Struct:
+			if nested := envValues(fieldType, variable+"_"); len(nested) > 0 {
+				values[name] = nested
+			}
+		case reflect.Map:
+			if fieldType.Elem().Kind() != reflect.String {
+				continue
+			}
+
+			if nested := envMap(variable + "_"); len(nested) > 0 {
+				values[name] = nested
+			}
+		case reflect.String:
+			if value, ok := os.LookupEnv(variable); ok {
+				values[name] = value
+			}
+		default:
+			if value, ok := os.LookupEnv(variable); ok {
+				values[name] = envScalar(value)
+			}
+		}
+	}
+	return values
+}
+
+// envMap returns the environment variables with the prefix by their lower case name without the prefix, __ in the
+// name is replaced by . for the dotted keys e.g. TESTKIT_KAFKA_CLIENT_ISOLATION__LEVEL for isolation.level
+func envMap(prefix string) map[string]any {
+	values := make(map[string]any)
+	for _, env := range os.Environ() {
+		name, value, _ := strings.Cut(env, "=")
+		if key, ok := strings.CutPrefix(name, prefix); ok && key != "" {
+			values[strings.ToLower(strings.ReplaceAll(key, "__", "."))] = value
+		}
+	}
+	return values
+}
+
+// envScalar returns the value resolved as a yaml scalar e.g. 3 is a number and true a boolean
+func envScalar(value string) any {
+	var resolved any
+	if err := yaml.Unmarshal([]byte(value), &resolved); err != nil || resolved == nil {
+		return value
+	}
+	return resolved
+}
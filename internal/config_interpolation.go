@@ -0,0 +1,108 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+const secretFilePrefix = "file://"
+
+// variablePattern matches ${VAR} and ${VAR:-default}, $${VAR} escapes the reference
+var variablePattern = regexp.MustCompile(`\$(\$?)\{([A-Za-z_][A-Za-z0-9_]*)(?::-([^}]*))?}`)
+
+// interpolate replaces the environment variable references and the secret file references in the string values of
+// the config. The content is returned as is when it does not reference any variable or secret file
+func interpolate(content []byte) ([]byte, error) {
+	var root yaml.Node
+	if err := yaml.Unmarshal(content, &root); err != nil {
+		return nil, err
+	}
+
+	interpolator := &configInterpolator{}
+	interpolator.walk(&root)
+
+	if len(interpolator.unresolved) > 0 {
+		return nil, errors.Errorf("unresolved variables: %s", strings.Join(interpolator.unresolved, ", "))
+	}
+
+	if len(interpolator.errors) > 0 {
+		return nil, errors.New(strings.Join(interpolator.errors, ", "))
+	}
+
+	if !interpolator.changed {
+		return content, nil
+	}
+	return yaml.Marshal(&root)
+}
+
+// configInterpolator collects the unresolved variables and the errors while walking the config
+type configInterpolator struct {
+	changed    bool
+	unresolved []string
+	errors     []string
+}
+
+// walk interpolates the string values of the node and its children, the keys of the mappings are left unchanged
+func (c *configInterpolator) walk(node *yaml.Node) {
+	switch node.Kind {
+	case yaml.DocumentNode, yaml.SequenceNode:
+		for _, child := range node.Content {
+			c.walk(child)
+		}
+	case yaml.MappingNode:
+		for i := 1; i < len(node.Content); i += 2 {
+			c.walk(node.Content[i])
+		}
+	case yaml.ScalarNode:
+		if node.Tag == "!!str" {
+			c.interpolateScalar(node)
+		}
+	}
+}
+
+// interpolateScalar replaces the variables of the value and reads the secret file it references
+func (c *configInterpolator) interpolateScalar(node *yaml.Node) {
+	value := variablePattern.ReplaceAllStringFunc(node.Value, func(match string) string {
+		groups := variablePattern.FindStringSubmatch(match)
+		if groups[1] != "" {
+			return match[1:]
+		}
+
+		name, hasDefault := groups[2], strings.Contains(match, ":-")
+		env, ok := os.LookupEnv(name)
+		switch {
+		case hasDefault && env == "":
+			return groups[3]
+		case !ok:
+			c.unresolved = append(c.unresolved, fmt.Sprintf("%s (line %d)", name, node.Line))
+			return match
+		default:
+			return env
+		}
+	})
+
+	if path, ok := strings.CutPrefix(value, secretFilePrefix); ok {
+		secret, err := os.ReadFile(path)
+		if err != nil {
+			c.errors = append(c.errors, fmt.Sprintf("failed to read secret file %s (line %d): %v", path, node.Line, err))
+			return
+		}
+		value = strings.TrimRight(string(secret), "\r\n")
+	}
+
+	if value == node.Value {
+		return
+	}
+
+	c.changed = true
+	node.Value = value
+	// plain values are resolved again e.g. ${PORT:-5432} is decoded as a number
+	if node.Style == 0 {
+		node.Tag = ""
+	}
+}
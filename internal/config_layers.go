@@ -0,0 +1,133 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+const localOverlay = "local"
+
+// configLayer is a config file merged into the effective config
+type configLayer struct {
+	path    string
+	content []byte
+}
+
+// EffectiveConfig is the config file deep merged with its profile and local overlays
+type EffectiveConfig struct {
+	Files   []string          // Files are the config files merged in order, the later ones override the earlier ones
+	Values  map[string]any    // Values are the merged values of the config files
+	Sources map[string]string // Sources are the files each value came from by dotted key e.g. postgres.query_params.sslmode
+}
+
+// layers returns the config file followed by its overlays
+func (c *cache) layers() []configLayer {
+	return append([]configLayer{{path: c.path, content: c.content}}, c.overlays...)
+}
+
+// readOverlays reads the overlays of the config file, the files of the profiles in TESTKIT_PROFILE followed by the
+// local file. The overlays are next to the config file e.g. .testkit.config.ci.yml and .testkit.config.local.yml
+// for .testkit.config.yml, a missing profile file is an error while the local file is optional
+func readOverlays(path string) ([]configLayer, error) {
+	overlays := make([]configLayer, 0)
+	for _, profile := range strings.Split(os.Getenv(EnvProfile), ",") {
+		profile = strings.TrimSpace(profile)
+		if profile == "" {
+			continue
+		}
+
+		overlay := overlayPath(path, profile)
+		content, err := os.ReadFile(overlay)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read config file of profile %s", profile)
+		}
+		overlays = append(overlays, configLayer{path: overlay, content: content})
+	}
+
+	local := overlayPath(path, localOverlay)
+	content, err := os.ReadFile(local)
+	switch {
+	case err == nil:
+		overlays = append(overlays, configLayer{path: local, content: content})
+	case !errors.Is(err, os.ErrNotExist):
+		return nil, errors.Wrap(err, "failed to read local config file")
+	}
+	return overlays, nil
+}
+
+// overlayPath returns the path of the overlay of the config file e.g. .testkit.config.ci.yml
+func overlayPath(path, name string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + name + ext
+}
+
+// mergeConfigLayers interpolates the layers and deep merges them in order, the mappings are merged key by key
+// while the other values of the later layers replace the earlier ones
+func mergeConfigLayers(layers []configLayer) (EffectiveConfig, error) {
+	effective := EffectiveConfig{
+		Values:  make(map[string]any),
+		Sources: make(map[string]string),
+	}
+
+	for _, layer := range layers {
+		content, err := interpolate(layer.content)
+		if err != nil {
+			return EffectiveConfig{}, errors.Wrapf(err, "failed to interpolate config file %s", layer.path)
+		}
+
+		var values map[string]any
+		if err = yaml.Unmarshal(content, &values); err != nil {
+			return EffectiveConfig{}, errors.Wrapf(err, "failed to unmarshal config from file %s", layer.path)
+		}
+
+		effective.Files = append(effective.Files, layer.path)
+		mergeValues(effective.Values, values, layer.path, "", effective.Sources)
+	}
+	return effective, nil
+}
+
+// mergeValues deep merges the values of the file into the merged values and records the source of each value
+func mergeValues(merged, values map[string]any, file, prefix string, sources map[string]string) {
+	for key, value := range values {
+		path := joinKey(prefix, key)
+		existing, existingIsMap := merged[key].(map[string]any)
+		overlay, overlayIsMap := value.(map[string]any)
+		if existingIsMap && overlayIsMap {
+			mergeValues(existing, overlay, file, path, sources)
+			continue
+		}
+
+		for source := range sources {
+			if source == path || strings.HasPrefix(source, path+".") {
+				delete(sources, source)
+			}
+		}
+
+		merged[key] = value
+		recordSources(value, file, path, sources)
+	}
+}
+
+// recordSources records the file as the source of the value and of its nested values
+func recordSources(value any, file, path string, sources map[string]string) {
+	nested, ok := value.(map[string]any)
+	if !ok || len(nested) == 0 {
+		sources[path] = file
+		return
+	}
+
+	for key, child := range nested {
+		recordSources(child, file, joinKey(path, key), sources)
+	}
+}
+
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
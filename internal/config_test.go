@@ -4,6 +4,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 
@@ -89,6 +90,201 @@ key-2: value-2
 	}
 }
 
+func Test_ReadConfigAs_Interpolation(t *testing.T) {
+	t.Cleanup(unsetEnvVar)
+	_ = os.Setenv(internal.EnvDisableConfigCache, "true")
+	t.Setenv("TESTKIT_TEST_USER", "testkit")
+	t.Setenv("TESTKIT_TEST_EMPTY", "")
+
+	secret := filepath.Join(t.TempDir(), "password")
+	require.NoError(t, os.WriteFile(secret, []byte("badger\n"), 0600))
+	t.Setenv("TESTKIT_TEST_SECRET_FILE", secret)
+
+	type Result map[string]any
+
+	testCases := []struct {
+		name        string
+		fileContent string
+		want        Result
+		wantErr     string
+	}{
+		{
+			name:        "should replace environment variables",
+			fileContent: `user: ${TESTKIT_TEST_USER}`,
+			want:        Result{"user": "testkit"},
+		},
+		{
+			name:        "should use default when variable is empty or not set",
+			fileContent: "host: ${TESTKIT_TEST_EMPTY:-localhost}\nport: ${TESTKIT_TEST_PORT:-5432}",
+			want:        Result{"host": "localhost", "port": 5432},
+		},
+		{
+			name:        "should keep quoted values as string",
+			fileContent: `port: "${TESTKIT_TEST_PORT:-5432}"`,
+			want:        Result{"port": "5432"},
+		},
+		{
+			name:        "should read secret files",
+			fileContent: `password: file://${TESTKIT_TEST_SECRET_FILE}`,
+			want:        Result{"password": "badger"},
+		},
+		{
+			name:        "should not replace escaped variables",
+			fileContent: `value: $${TESTKIT_TEST_USER}`,
+			want:        Result{"value": "${TESTKIT_TEST_USER}"},
+		},
+		{
+			name:        "should list unresolved variables",
+			fileContent: "user: ${TESTKIT_TEST_MISSING_USER}\npassword: ${TESTKIT_TEST_MISSING_PASSWORD}",
+			wantErr:     "unresolved variables: TESTKIT_TEST_MISSING_USER (line 1), TESTKIT_TEST_MISSING_PASSWORD (line 2)",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			location := filepath.Join(t.TempDir(), "config.yml")
+			err := os.WriteFile(location, []byte(tc.fileContent), 0600)
+			require.NoError(t, err)
+			_ = os.Setenv(internal.EnvConfigLocation, location)
+
+			result, err := internal.ReadConfigAs[Result]()
+
+			if tc.wantErr != "" {
+				require.ErrorContains(t, err, tc.wantErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.want, result)
+		})
+	}
+}
+
+func Test_ReadEffectiveConfig(t *testing.T) {
+	t.Cleanup(unsetEnvVar)
+	_ = os.Setenv(internal.EnvDisableConfigCache, "true")
+
+	dir := t.TempDir()
+	base := filepath.Join(dir, ".testkit.config.yml")
+	profile := filepath.Join(dir, ".testkit.config.ci.yml")
+	local := filepath.Join(dir, ".testkit.config.local.yml")
+	require.NoError(t, os.WriteFile(base, []byte(`
+log_level: info
+postgres:
+  host: localhost:5432
+  query_params:
+    sslmode: disable
+    connect_timeout: 10
+`), 0600))
+	require.NoError(t, os.WriteFile(profile, []byte(`
+postgres:
+  host: postgres:5432
+  query_params:
+    sslmode: require
+`), 0600))
+	require.NoError(t, os.WriteFile(local, []byte(`log_level: debug`), 0600))
+	_ = os.Setenv(internal.EnvConfigLocation, base)
+	t.Setenv(internal.EnvProfile, "ci")
+
+	effective, err := internal.ReadEffectiveConfig()
+
+	require.NoError(t, err)
+	require.Equal(t, []string{base, profile, local}, effective.Files)
+	require.Equal(t, map[string]any{
+		"log_level": "debug",
+		"postgres": map[string]any{
+			"host": "postgres:5432",
+			"query_params": map[string]any{
+				"sslmode":         "require",
+				"connect_timeout": 10,
+			},
+		},
+	}, effective.Values)
+	require.Equal(t, map[string]string{
+		"log_level":                             local,
+		"postgres.host":                         profile,
+		"postgres.query_params.sslmode":         profile,
+		"postgres.query_params.connect_timeout": base,
+	}, effective.Sources)
+
+	content, err := internal.ReadConfigFile()
+	require.NoError(t, err)
+	require.Contains(t, string(content), "sslmode: require")
+}
+
+func Test_ReadEffectiveConfig_WhenProfileFileIsMissing(t *testing.T) {
+	t.Cleanup(unsetEnvVar)
+	_ = os.Setenv(internal.EnvDisableConfigCache, "true")
+	_ = os.Setenv(internal.EnvConfigLocation, "testdata/config.yml")
+	t.Setenv(internal.EnvProfile, "missing")
+
+	_, err := internal.ReadEffectiveConfig()
+
+	require.ErrorContains(t, err, "failed to read config file of profile missing")
+}
+
+func Test_ReadEffectiveConfig_WithEnvironmentVariables(t *testing.T) {
+	t.Cleanup(unsetEnvVar)
+	_ = os.Setenv(internal.EnvDisableConfigCache, "true")
+	_ = os.Setenv(internal.EnvConfigLocation, "testdata/config.yml")
+	t.Setenv("TESTKIT_POSTGRES_HOST", "postgres:5432")
+	t.Setenv("TESTKIT_POSTGRES_QUERY_PARAMS_SSLMODE", "disable")
+	t.Setenv("TESTKIT_RETRIES", "2")
+	t.Setenv("TESTKIT_API_MOCK_ADDRESS", "http://wiremock:8080")
+
+	effective, err := internal.ReadEffectiveConfig()
+
+	require.NoError(t, err)
+	require.Equal(t, []string{"testdata/config.yml", "environment"}, effective.Files)
+	require.Equal(t, map[string]any{
+		"foo":     map[string]any{"bar": "baz"},
+		"retries": 2,
+		"postgres": map[string]any{
+			"host":         "postgres:5432",
+			"query_params": map[string]any{"sslmode": "disable"},
+		},
+		"api-mock": map[string]any{"address": "http://wiremock:8080"},
+	}, effective.Values)
+	require.Equal(t, "environment", effective.Sources["postgres.host"])
+}
+
+func Test_ReadConfigAs_EnvironmentOnly(t *testing.T) {
+	t.Cleanup(unsetEnvVar)
+	_ = os.Setenv(internal.EnvDisableConfigCache, "true")
+	_ = os.Setenv(internal.EnvConfigLocation, "testdata/missing.yml")
+	t.Setenv(internal.EnvConfigMode, internal.ConfigModeEnv)
+	t.Setenv("TESTKIT_LOG_LEVEL", "debug")
+	t.Setenv("TESTKIT_TEST_TIMEOUT", "30s")
+	t.Setenv("TESTKIT_ELASTICSEARCH_ADDRESSES", "http://elasticsearch:9200")
+
+	config, err := internal.ReadConfigAs[internal.SuiteConfig]()
+
+	require.NoError(t, err)
+	require.Equal(t, internal.SuiteConfig{
+		LogLevel:      "debug",
+		TestTimeout:   30 * time.Second,
+		ElasticSearch: &internal.ElasticSearchConfig{Addresses: "http://elasticsearch:9200"},
+	}, config)
+}
+
+func Test_ReadConfigAs_EnvironmentOnly_DottedMapKeys(t *testing.T) {
+	t.Cleanup(unsetEnvVar)
+	_ = os.Setenv(internal.EnvDisableConfigCache, "true")
+	_ = os.Setenv(internal.EnvConfigLocation, "testdata/missing.yml")
+	t.Setenv(internal.EnvConfigMode, internal.ConfigModeEnv)
+	t.Setenv("TESTKIT_KAFKA_BOOTSTRAP_SERVERS", "kafka:9092")
+	t.Setenv("TESTKIT_KAFKA_CLIENT_ISOLATION__LEVEL", "read_committed")
+	t.Setenv("TESTKIT_KAFKA_CLIENT_ACKS", "all")
+
+	config, err := internal.ReadConfigAs[internal.SuiteConfig]()
+
+	require.NoError(t, err)
+	require.NotNil(t, config.Kafka)
+	require.Equal(t, map[string]string{
+		"isolation.level": "read_committed",
+		"acks":            "all",
+	}, config.Kafka.Client)
+}
+
 func unsetEnvVar() {
 	_ = os.Unsetenv(internal.EnvConfigLocation)
 	_ = os.Unsetenv(internal.EnvDisableConfigCache)
@@ -0,0 +1,331 @@
+package internal
+
+import (
+	"fmt"
+	"maps"
+	"net"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigErrors are all the problems found in the config
+type ConfigErrors []string
+
+// Error returns the problems one per line
+func (e ConfigErrors) Error() string {
+	return "invalid testkit config:\n  - " + strings.Join(e, "\n  - ")
+}
+
+// LoadSuiteConfig reads the effective config, rejects the unknown fields, applies the defaults from the struct tags
+// and validates the values. The top level sections listed in extraSections are accepted as is, e.g. the sections of
+// the custom providers. All the problems are returned at once as ConfigErrors
+func LoadSuiteConfig(extraSections []string) (SuiteConfig, map[string]any, error) {
+	var config SuiteConfig
+	effective, err := ReadEffectiveConfig()
+	if err != nil {
+		return config, nil, err
+	}
+
+	problems := unknownFields(effective.Values, reflect.TypeOf(config), "", extraSections)
+
+	content, err := yaml.Marshal(effective.Values)
+	if err != nil {
+		return config, nil, err
+	}
+
+	if err = yaml.Unmarshal(content, &config); err != nil {
+		var typeErr *yaml.TypeError
+		if !errors.As(err, &typeErr) {
+			return config, nil, err
+		}
+		problems = append(problems, typeErr.Errors...)
+	}
+
+	if err = ApplyDefaults(&config); err != nil {
+		return config, nil, err
+	}
+
+	_, hasPostgres := effective.Values["postgres"]
+	problems = append(problems, config.validate(hasPostgres)...)
+	if len(problems) > 0 {
+		return config, nil, ConfigErrors(problems)
+	}
+	return config, effective.Values, nil
+}
+
+// ValidateSuiteConfig validates the config built in code, the postgres section is validated when any of its fields
+// is set. All the problems are returned at once as ConfigErrors
+func ValidateSuiteConfig(config SuiteConfig) error {
+	hasPostgres := !reflect.ValueOf(config.PostgresConfig).IsZero()
+	if problems := config.validate(hasPostgres); len(problems) > 0 {
+		return ConfigErrors(problems)
+	}
+	return nil
+}
+
+// validate returns the problems of the config, the required fields are checked for the sections which are set
+func (c *SuiteConfig) validate(hasPostgres bool) []string {
+	var problems []string
+	if _, err := logrus.ParseLevel(c.LogLevel); err != nil {
+		problems = append(problems, fmt.Sprintf("log_level: %v", err))
+	}
+
+	if c.TestTimeout < 0 {
+		problems = append(problems, "test_timeout: must not be negative")
+	}
+
+	if c.Retries < 0 {
+		problems = append(problems, "retries: must not be negative")
+	}
+
+	if hasPostgres {
+		problems = append(problems, c.PostgresConfig.validate()...)
+	}
+
+	if c.GoMigrateConfig != nil {
+		problems = append(problems, requireFields("go-migrate", map[string]string{
+			"migration_path": c.GoMigrateConfig.MigrationPath,
+			"database_name":  c.GoMigrateConfig.DatabaseName,
+		})...)
+		if c.PostgresConfig.Host == "" {
+			problems = append(problems, "go-migrate: requires the postgres section")
+		}
+	}
+
+	if c.ElasticSearch != nil {
+		problems = append(problems, c.ElasticSearch.validate("elasticsearch")...)
+	}
+
+	if c.OpenSearch != nil {
+		problems = append(problems, c.OpenSearch.validate("opensearch")...)
+	}
+
+	if c.APIMockConfig != nil {
+		if err := validateURL(c.APIMockConfig.Address); err != nil {
+			problems = append(problems, fmt.Sprintf("api-mock.address: %v", err))
+		}
+	}
+
+	if c.Kafka != nil {
+		problems = append(problems, c.Kafka.validate()...)
+	}
+
+	problems = append(problems, c.Readiness.validate("readiness")...)
+	for _, backend := range slices.Sorted(maps.Keys(c.Readiness.Backends)) {
+		problems = append(problems, c.Readiness.Backends[backend].validate("readiness.backends."+backend)...)
+	}
+	return problems
+}
+
+// validate returns the problems of the kafka section
+func (c *KafkaConfig) validate() []string {
+	var problems []string
+	switch c.Mode {
+	case KafkaModeMock:
+	case KafkaModeBroker:
+		problems = append(problems, requireFields("kafka", map[string]string{"bootstrap_servers": c.BootstrapServers})...)
+	default:
+		problems = append(problems, fmt.Sprintf("kafka.mode: must be %s or %s, got %q", KafkaModeMock, KafkaModeBroker, c.Mode))
+	}
+
+	protocols := []string{"PLAINTEXT", "SSL", "SASL_PLAINTEXT", "SASL_SSL"}
+	if c.SecurityProtocol != "" && !slices.Contains(protocols, strings.ToUpper(c.SecurityProtocol)) {
+		problems = append(problems, fmt.Sprintf("kafka.security_protocol: must be one of %s, got %q", strings.Join(protocols, ", "), c.SecurityProtocol))
+	}
+
+	if c.SASL != nil {
+		problems = append(problems, requireFields("kafka.sasl", map[string]string{
+			"username": c.SASL.Username,
+			"password": c.SASL.Password,
+		})...)
+	}
+
+	if c.Partitions < 1 {
+		problems = append(problems, "kafka.partitions: must be positive")
+	}
+
+	if c.ReplicationFactor < 1 {
+		problems = append(problems, "kafka.replication_factor: must be positive")
+	}
+	return problems
+}
+
+// validate returns a problem for each negative duration of the backoff
+func (c BackoffConfig) validate(section string) []string {
+	var problems []string
+	for name, value := range map[string]time.Duration{"timeout": c.Timeout, "interval": c.Interval, "max_interval": c.MaxInterval} {
+		if value < 0 {
+			problems = append(problems, fmt.Sprintf("%s.%s: must not be negative", section, name))
+		}
+	}
+	slices.Sort(problems)
+	return problems
+}
+
+// validate returns the problems of the postgres section
+func (c *PostgresConfig) validate() []string {
+	problems := requireFields("postgres", map[string]string{
+		"host": c.Host,
+		"user": c.User,
+	})
+
+	if c.Host != "" {
+		if _, port, err := net.SplitHostPort(c.Host); err != nil {
+			problems = append(problems, fmt.Sprintf("postgres.host: must be host:port, got %q", c.Host))
+		} else if _, err = strconv.Atoi(port); err != nil {
+			problems = append(problems, fmt.Sprintf("postgres.host: invalid port %q", port))
+		}
+	}
+	return problems
+}
+
+// validate returns the problems of the elasticsearch or opensearch section
+func (c *ElasticSearchConfig) validate(section string) []string {
+	problems := requireFields(section, map[string]string{"addresses": c.Addresses})
+	for _, address := range strings.Split(c.Addresses, ",") {
+		if strings.TrimSpace(address) == "" {
+			continue
+		}
+
+		if err := validateURL(strings.TrimSpace(address)); err != nil {
+			problems = append(problems, fmt.Sprintf("%s.addresses: %v", section, err))
+		}
+	}
+	return problems
+}
+
+// requireFields returns a problem for each empty field of the section
+func requireFields(section string, fields map[string]string) []string {
+	var problems []string
+	for _, name := range slices.Sorted(maps.Keys(fields)) {
+		if strings.TrimSpace(fields[name]) == "" {
+			problems = append(problems, fmt.Sprintf("%s.%s: is required", section, name))
+		}
+	}
+	return problems
+}
+
+// validateURL returns an error if the address is not an absolute http or https URL
+func validateURL(address string) error {
+	u, err := url.Parse(address)
+	if err != nil {
+		return errors.Errorf("invalid URL %q", address)
+	}
+
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.Errorf("must be an http or https URL, got %q", address)
+	}
+	return nil
+}
+
+// unknownFields returns a problem for each key of the values which does not match a yaml field of the struct type,
+// the fields are listed in the problem to spot the typos
+func unknownFields(values map[string]any, typ reflect.Type, prefix string, extraSections []string) []string {
+	fields := yamlFields(typ)
+	var problems []string
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		path := joinKey(prefix, key)
+		field, ok := fields[key]
+		if !ok {
+			if prefix == "" && slices.Contains(extraSections, key) {
+				continue
+			}
+
+			known := slices.Sorted(maps.Keys(fields))
+			problems = append(problems, fmt.Sprintf("%s: unknown field, expected one of %s", path, strings.Join(known, ", ")))
+			continue
+		}
+
+		nested, isMap := values[key].(map[string]any)
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Pointer {
+			fieldType = fieldType.Elem()
+		}
+
+		switch {
+		case !isMap:
+		case fieldType.Kind() == reflect.Struct:
+			problems = append(problems, unknownFields(nested, fieldType, path, nil)...)
+		case fieldType.Kind() == reflect.Map && fieldType.Elem().Kind() == reflect.Struct:
+			for _, name := range slices.Sorted(maps.Keys(nested)) {
+				if entry, ok := nested[name].(map[string]any); ok {
+					problems = append(problems, unknownFields(entry, fieldType.Elem(), joinKey(path, name), nil)...)
+				}
+			}
+		}
+	}
+	return problems
+}
+
+// yamlFields returns the fields of the struct type by their yaml name
+func yamlFields(typ reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := range typ.NumField() {
+		field := typ.Field(i)
+		name, options, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+
+		if options == "inline" && field.Type.Kind() == reflect.Struct {
+			maps.Copy(fields, yamlFields(field.Type))
+			continue
+		}
+
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fields[name] = field
+	}
+	return fields
+}
+
+// ApplyDefaults sets the fields of the config struct pointed by config which are not set to their default value
+func ApplyDefaults(config any) error {
+	return applyDefaults(reflect.ValueOf(config).Elem())
+}
+
+// applyDefaults sets the zero fields of the struct to the value of their default tag, the nested structs are
+// visited when they are set
+func applyDefaults(value reflect.Value) error {
+	for i := range value.NumField() {
+		field := value.Field(i)
+		structField := value.Type().Field(i)
+		if !structField.IsExported() {
+			continue
+		}
+
+		switch {
+		case field.Kind() == reflect.Struct:
+			if err := applyDefaults(field); err != nil {
+				return err
+			}
+			continue
+		case field.Kind() == reflect.Pointer && field.Type().Elem().Kind() == reflect.Struct:
+			if !field.IsNil() {
+				if err := applyDefaults(field.Elem()); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		def, ok := structField.Tag.Lookup("default")
+		if !ok || !field.IsZero() {
+			continue
+		}
+
+		if err := yaml.Unmarshal([]byte(def), field.Addr().Interface()); err != nil {
+			return errors.Wrapf(err, "invalid default %q of field %s", def, structField.Name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,176 @@
+package internal_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bdpiprava/testkit/internal"
+)
+
+func Test_LoadSuiteConfig(t *testing.T) {
+	t.Cleanup(unsetEnvVar)
+	_ = os.Setenv(internal.EnvDisableConfigCache, "true")
+
+	testCases := []struct {
+		name        string
+		fileContent string
+		want        internal.SuiteConfig
+		wantErrors  []string
+	}{
+		{
+			name: "should apply the defaults",
+			fileContent: `
+api-mock: {}
+`,
+			want: internal.SuiteConfig{
+				LogLevel:      "info",
+				APIMockConfig: &internal.APIMockConfig{Address: "http://localhost:8080"},
+				Readiness:     readinessDefaults,
+			},
+		},
+		{
+			name: "should accept the sections of the registered providers",
+			fileContent: `
+log_level: debug
+postgres:
+  host: localhost:5432
+  user: testkit
+  database: testkit_db
+custom:
+  foo: bar
+`,
+			want: internal.SuiteConfig{
+				LogLevel:  "debug",
+				Readiness: readinessDefaults,
+				PostgresConfig: internal.PostgresConfig{
+					Host:     "localhost:5432",
+					User:     "testkit",
+					Database: "testkit_db",
+				},
+			},
+		},
+		{
+			name: "should list all the problems",
+			fileContent: `
+log_level: loud
+retries: -1
+postgres:
+  host: localhost
+  name: testkit_db
+go-migrate:
+  fresh: true
+elasticsearch:
+  addresses: localhost:9200
+api-mock:
+  address: localhost:8080
+kafka:
+  mode: cluster
+readiness:
+  timeout: -1s
+  backends:
+    postgres:
+      timout: 1m
+unknown: true
+`,
+			wantErrors: []string{
+				"postgres.name: unknown field, expected one of database, from_template, host, password, query_params, user",
+				"readiness.backends.postgres.timout: unknown field, expected one of interval, max_interval, timeout",
+				"unknown: unknown field, expected one of api-mock, elasticsearch, go-migrate, kafka, log_level, opensearch, postgres, readiness, retries, test_timeout",
+				`log_level: not a valid logrus Level: "loud"`,
+				"retries: must not be negative",
+				"postgres.user: is required",
+				`postgres.host: must be host:port, got "localhost"`,
+				"go-migrate.database_name: is required",
+				"go-migrate.migration_path: is required",
+				`elasticsearch.addresses: must be an http or https URL, got "localhost:9200"`,
+				`api-mock.address: must be an http or https URL, got "localhost:8080"`,
+				`kafka.mode: must be mock or broker, got "cluster"`,
+				"readiness.timeout: must not be negative",
+			},
+		},
+		{
+			name: "should report the invalid types",
+			fileContent: `
+retries: many
+`,
+			wantErrors: []string{"line 1: cannot unmarshal !!str `many` into int"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), ".testkit.config.yml")
+			require.NoError(t, os.WriteFile(path, []byte(tc.fileContent), 0600))
+			_ = os.Setenv(internal.EnvConfigLocation, path)
+
+			got, _, err := internal.LoadSuiteConfig([]string{"custom"})
+
+			if len(tc.wantErrors) > 0 {
+				var problems internal.ConfigErrors
+				require.ErrorAs(t, err, &problems)
+				require.Equal(t, tc.wantErrors, []string(problems))
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
+
+var readinessDefaults = internal.ReadinessConfig{
+	BackoffConfig: internal.BackoffConfig{
+		Timeout:     30 * time.Second,
+		Interval:    500 * time.Millisecond,
+		MaxInterval: 5 * time.Second,
+	},
+}
+
+func Test_ReadinessConfig_For(t *testing.T) {
+	config := internal.ReadinessConfig{
+		BackoffConfig: readinessDefaults.BackoffConfig,
+		Backends: map[string]internal.BackoffConfig{
+			"postgres": {Timeout: time.Minute},
+		},
+	}
+
+	require.Equal(t, internal.BackoffConfig{
+		Timeout:     time.Minute,
+		Interval:    500 * time.Millisecond,
+		MaxInterval: 5 * time.Second,
+	}, config.For("postgres"))
+	require.Equal(t, readinessDefaults.BackoffConfig, config.For("elasticsearch"))
+}
+
+func Test_ConfigSchema_CoversSuiteConfig(t *testing.T) {
+	content, err := os.ReadFile("../testkit.config.schema.json")
+	require.NoError(t, err)
+
+	var schema map[string]any
+	require.NoError(t, json.Unmarshal(content, &schema))
+
+	properties := schema["properties"].(map[string]any)
+	for _, name := range yamlNames(reflect.TypeOf(internal.SuiteConfig{})) {
+		require.Contains(t, properties, name)
+	}
+
+	postgres := properties["postgres"].(map[string]any)["properties"].(map[string]any)
+	for _, name := range yamlNames(reflect.TypeOf(internal.PostgresConfig{})) {
+		require.Contains(t, postgres, name)
+	}
+}
+
+func yamlNames(typ reflect.Type) []string {
+	names := make([]string, 0, typ.NumField())
+	for i := range typ.NumField() {
+		name, _, _ := strings.Cut(typ.Field(i).Tag.Get("yaml"), ",")
+		names = append(names, name)
+	}
+	return names
+}
@@ -1,6 +1,7 @@
 package internal
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -44,14 +45,15 @@ func InitialiseDatabase(config SuiteConfig, log logrus.FieldLogger) (*sqlx.DB, e
 		return nil, errors.Wrap(err, "go migrate failed")
 	}
 
-	root, err := postgresDB.connect(rootDatabase)
+	ctx := context.Background()
+	root, err := postgresDB.connect(ctx, rootDatabase)
 	if err != nil {
 		log.WithError(err).Errorf("failed to connect to %s database", rootDatabase)
 		return nil, err
 	}
 	defer closeSilently(root)
 
-	exists, err := postgresDB.exists(root, cfg.DatabaseName)
+	exists, err := postgresDB.exists(ctx, root, cfg.DatabaseName)
 	if err != nil {
 		log.WithError(err).Errorf("failed to check database exist for %s", cfg.DatabaseName)
 		return nil, err
@@ -60,7 +62,7 @@ func InitialiseDatabase(config SuiteConfig, log logrus.FieldLogger) (*sqlx.DB, e
 	if exists {
 		if !cfg.Fresh {
 			log.Debugf("template database '%s' already exists, returning...", cfg.DatabaseName)
-			return postgresDB.connect(cfg.DatabaseName)
+			return postgresDB.connect(ctx, cfg.DatabaseName)
 		}
 
 		log.Info("exist but requested fresh database, hence deleting the existing database")
@@ -95,7 +97,7 @@ func InitialiseDatabase(config SuiteConfig, log logrus.FieldLogger) (*sqlx.DB, e
 		return nil, errors.Wrap(err, "failed to apply migrations")
 	}
 
-	return postgresDB.connect(cfg.DatabaseName)
+	return postgresDB.connect(ctx, cfg.DatabaseName)
 }
 
 // resolveMigrationPath returns migration path after resolving the $PROJECT_ROOT placeholder
@@ -0,0 +1,125 @@
+// Package kafkamock creates the librdkafka mock clusters of the tests and exposes their controls, including the ones
+// which are not exposed by kafka.MockCluster. The librdkafka linked by confluent-kafka-go provides the functions
+package kafkamock
+
+/*
+#include <stdlib.h>
+#include <stdint.h>
+
+typedef struct rd_kafka_s rd_kafka_t;
+typedef struct rd_kafka_conf_s rd_kafka_conf_t;
+typedef struct rd_kafka_mock_cluster_s rd_kafka_mock_cluster_t;
+typedef enum { RD_KAFKA_PRODUCER, RD_KAFKA_CONSUMER } rd_kafka_type_t;
+typedef int rd_kafka_resp_err_t;
+
+extern rd_kafka_conf_t *rd_kafka_conf_new(void);
+extern void rd_kafka_conf_destroy(rd_kafka_conf_t *conf);
+extern rd_kafka_t *rd_kafka_new(rd_kafka_type_t type, rd_kafka_conf_t *conf, char *errstr, size_t errstr_size);
+extern void rd_kafka_destroy(rd_kafka_t *rk);
+
+extern rd_kafka_mock_cluster_t *rd_kafka_mock_cluster_new(rd_kafka_t *rk, int broker_cnt);
+extern void rd_kafka_mock_cluster_destroy(rd_kafka_mock_cluster_t *mcluster);
+extern const char *rd_kafka_mock_cluster_bootstraps(const rd_kafka_mock_cluster_t *mcluster);
+extern rd_kafka_resp_err_t rd_kafka_mock_topic_create(rd_kafka_mock_cluster_t *mcluster, const char *topic, int partition_cnt, int replication_factor);
+extern rd_kafka_resp_err_t rd_kafka_mock_broker_set_down(rd_kafka_mock_cluster_t *mcluster, int32_t broker_id);
+extern rd_kafka_resp_err_t rd_kafka_mock_broker_set_up(rd_kafka_mock_cluster_t *mcluster, int32_t broker_id);
+extern rd_kafka_resp_err_t rd_kafka_mock_broker_set_rtt(rd_kafka_mock_cluster_t *mcluster, int32_t broker_id, int rtt_ms);
+extern void rd_kafka_mock_push_request_errors_array(rd_kafka_mock_cluster_t *mcluster, int16_t ApiKey, size_t cnt, const rd_kafka_resp_err_t *errors);
+*/
+import "C"
+
+import (
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/pkg/errors"
+)
+
+const errstrSize = 512
+
+// Cluster is a librdkafka mock cluster, it owns the handle of the mock cluster and of the client instance hosting it
+type Cluster struct {
+	rk       *C.rd_kafka_t
+	mcluster *C.rd_kafka_mock_cluster_t
+	close    sync.Once
+}
+
+// NewCluster creates a mock cluster with the number of brokers, the brokers are numbered from 1
+func NewCluster(brokers int) (*Cluster, error) {
+	errstr := (*C.char)(C.malloc(errstrSize))
+	defer C.free(unsafe.Pointer(errstr))
+
+	conf := C.rd_kafka_conf_new()
+	rk := C.rd_kafka_new(C.RD_KAFKA_PRODUCER, conf, errstr, errstrSize)
+	if rk == nil {
+		C.rd_kafka_conf_destroy(conf)
+		return nil, errors.Errorf("failed to create the client of the mock cluster: %s", C.GoString(errstr))
+	}
+
+	mcluster := C.rd_kafka_mock_cluster_new(rk, C.int(brokers))
+	if mcluster == nil {
+		C.rd_kafka_destroy(rk)
+		return nil, errors.Errorf("failed to create the mock cluster with %d brokers", brokers)
+	}
+	return &Cluster{rk: rk, mcluster: mcluster}, nil
+}
+
+// BootstrapServers returns the bootstrap.servers of the clients connecting to the cluster
+func (c *Cluster) BootstrapServers() string {
+	return C.GoString(C.rd_kafka_mock_cluster_bootstraps(c.mcluster))
+}
+
+// CreateTopic creates the topic with the number of partitions and the replication factor
+func (c *Cluster) CreateTopic(topic string, partitions, replicationFactor int) error {
+	name := C.CString(topic)
+	defer C.free(unsafe.Pointer(name))
+	return asError(C.rd_kafka_mock_topic_create(c.mcluster, name, C.int(partitions), C.int(replicationFactor)))
+}
+
+// SetBrokerDown disconnects the clients from the broker and refuses the new connections, -1 sets all the brokers down
+func (c *Cluster) SetBrokerDown(brokerID int) error {
+	return asError(C.rd_kafka_mock_broker_set_down(c.mcluster, C.int32_t(brokerID)))
+}
+
+// SetBrokerUp accepts the connections to the broker again, -1 sets all the brokers up
+func (c *Cluster) SetBrokerUp(brokerID int) error {
+	return asError(C.rd_kafka_mock_broker_set_up(c.mcluster, C.int32_t(brokerID)))
+}
+
+// SetRoundtripDuration delays the responses of the broker by the round trip time, -1 delays all the brokers
+func (c *Cluster) SetRoundtripDuration(brokerID int, rtt time.Duration) error {
+	return asError(C.rd_kafka_mock_broker_set_rtt(c.mcluster, C.int32_t(brokerID), C.int(rtt.Milliseconds())))
+}
+
+// PushRequestErrors makes the next requests of the API key to the cluster fail with the errors, one error per request
+// in order. The API key is the Kafka protocol request type e.g. 0 for Produce
+func (c *Cluster) PushRequestErrors(apiKey int16, errs ...kafka.ErrorCode) error {
+	if len(errs) == 0 {
+		return errors.New("no error is given")
+	}
+
+	codes := make([]C.rd_kafka_resp_err_t, len(errs))
+	for i, err := range errs {
+		codes[i] = C.rd_kafka_resp_err_t(err)
+	}
+	C.rd_kafka_mock_push_request_errors_array(c.mcluster, C.int16_t(apiKey), C.size_t(len(codes)), &codes[0])
+	return nil
+}
+
+// Close destroys the mock cluster and its client instance
+func (c *Cluster) Close() {
+	c.close.Do(func() {
+		C.rd_kafka_mock_cluster_destroy(c.mcluster)
+		C.rd_kafka_destroy(c.rk)
+	})
+}
+
+// asError returns the kafka error of the librdkafka error code, nil when there is no error
+func asError(code C.rd_kafka_resp_err_t) error {
+	if code == 0 {
+		return nil
+	}
+	return kafka.NewError(kafka.ErrorCode(code), kafka.ErrorCode(code).String(), false)
+}
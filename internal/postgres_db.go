@@ -38,14 +38,14 @@ func (p *PostgresDB) DSN(name string) string {
 
 // connect returns a connection to the database
 // ensure that connection is established by making a ping request
-func (p *PostgresDB) connect(name string) (*sqlx.DB, error) {
+func (p *PostgresDB) connect(ctx context.Context, name string) (*sqlx.DB, error) {
 	dsn := p.DSN(name)
-	root, err := sqlx.Connect("postgres", dsn)
+	root, err := sqlx.ConnectContext(ctx, "postgres", dsn)
 	if err != nil {
 		return nil, errors.Wrapf(err, "[%s] failed to connect to database", name)
 	}
 
-	err = root.Ping()
+	err = root.PingContext(ctx)
 	if err != nil {
 		return nil, errors.Wrapf(err, "[%s] ping database failed", name)
 	}
@@ -53,9 +53,18 @@ func (p *PostgresDB) connect(name string) (*sqlx.DB, error) {
 	return root, nil
 }
 
+// Ping returns an error if the postgres server is not reachable
+func (p *PostgresDB) Ping(ctx context.Context) error {
+	root, err := p.connect(ctx, rootDatabase)
+	if err != nil {
+		return err
+	}
+	return root.Close()
+}
+
 // Delete deletes a database with the given name
 func (p *PostgresDB) Delete(name string) error {
-	root, err := p.connect(rootDatabase)
+	root, err := p.connect(context.Background(), rootDatabase)
 	if err != nil {
 		return err
 	}
@@ -67,7 +76,7 @@ func (p *PostgresDB) Delete(name string) error {
 
 // deleteTemplateDB removes the template database
 func (p *PostgresDB) deleteTemplateDB(name string) error {
-	root, err := p.connect(rootDatabase)
+	root, err := p.connect(context.Background(), rootDatabase)
 	if err != nil {
 		return err
 	}
@@ -82,7 +91,7 @@ func (p *PostgresDB) deleteTemplateDB(name string) error {
 	return err
 }
 
-// CreateDatabase creates a new target database from the template database
+// CreateDatabase creates a new target database from the template database, it fails if the database already exists
 func (p *PostgresDB) CreateDatabase(ctx context.Context, targetName string, log logrus.FieldLogger) (*sqlx.DB, error) {
 	log = log.WithFields(logrus.Fields{
 		"step":     "CreateDatabase",
@@ -90,16 +99,19 @@ func (p *PostgresDB) CreateDatabase(ctx context.Context, targetName string, log
 		"template": p.config.FromTemplate,
 	})
 
-	root, err := p.connect(rootDatabase)
+	root, err := p.connect(ctx, rootDatabase)
 	if err != nil {
 		return nil, err
 	}
 	defer closeSilently(root)
 
-	// return on error or if the database already exists
-	if exists, err := p.exists(root, targetName); err != nil || exists {
-		log.Info("Database already exists")
-		return p.connect(targetName)
+	exists, err := p.exists(ctx, root, targetName)
+	if err != nil {
+		return nil, errors.Wrapf(err, "[%s] failed to check if the database exists", targetName)
+	}
+
+	if exists {
+		return nil, errors.Errorf("[%s] database already exists", targetName)
 	}
 
 	if len(p.config.FromTemplate) > 0 {
@@ -109,7 +121,7 @@ func (p *PostgresDB) CreateDatabase(ctx context.Context, targetName string, log
 			return nil, errors.Wrap(err, "failed to create database from template")
 		}
 
-		return p.connect(targetName)
+		return p.connect(ctx, targetName)
 	}
 
 	log.Info("Creating new database from scratch")
@@ -118,13 +130,13 @@ func (p *PostgresDB) CreateDatabase(ctx context.Context, targetName string, log
 		return nil, errors.Wrap(err, "failed to create database from scratch")
 	}
 
-	return p.connect(targetName)
+	return p.connect(ctx, targetName)
 }
 
 // exists checks if the database exists
-func (p *PostgresDB) exists(db *sqlx.DB, name string) (bool, error) {
+func (p *PostgresDB) exists(ctx context.Context, db *sqlx.DB, name string) (bool, error) {
 	var exists bool
-	err := db.Get(&exists, fmt.Sprintf(databaseExistsQuery, name))
+	err := db.GetContext(ctx, &exists, fmt.Sprintf(databaseExistsQuery, name))
 	return exists, err
 }
 
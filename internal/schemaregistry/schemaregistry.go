@@ -0,0 +1,462 @@
+// Package schemaregistry is an in process stand-in of the Confluent schema registry, it implements the subjects,
+// versions, schemas by id, compatibility and config endpoints of its REST API
+package schemaregistry
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hamba/avro/v2"
+	"github.com/pkg/errors"
+)
+
+const contentType = "application/vnd.schemaregistry.v1+json"
+
+// Schema types
+const (
+	TypeAvro     = "AVRO"
+	TypeProtobuf = "PROTOBUF"
+	TypeJSON     = "JSON"
+)
+
+// Compatibility levels
+const (
+	None               = "NONE"
+	Backward           = "BACKWARD"
+	BackwardTransitive = "BACKWARD_TRANSITIVE"
+	Forward            = "FORWARD"
+	ForwardTransitive  = "FORWARD_TRANSITIVE"
+	Full               = "FULL"
+	FullTransitive     = "FULL_TRANSITIVE"
+)
+
+var compatibilityLevels = []string{None, Backward, BackwardTransitive, Forward, ForwardTransitive, Full, FullTransitive}
+
+// error codes of the REST API
+const (
+	codeSubjectNotFound      = 40401
+	codeVersionNotFound      = 40402
+	codeSchemaNotFound       = 40403
+	codeIncompatibleSchema   = 409
+	codeInvalidSchema        = 42201
+	codeInvalidVersion       = 42202
+	codeInvalidCompatibility = 42203
+)
+
+// apiError is an error response of the REST API
+type apiError struct {
+	status  int
+	Code    int    `json:"error_code"`
+	Message string `json:"message"`
+}
+
+// Error returns the message of the error
+func (e *apiError) Error() string {
+	return e.Message
+}
+
+// schema is a schema registered with its id
+type schema struct {
+	ID         int    `json:"id"`
+	SchemaType string `json:"schemaType"`
+	Schema     string `json:"schema"`
+}
+
+// subjectVersion is a version of a subject
+type subjectVersion struct {
+	Subject    string `json:"subject"`
+	ID         int    `json:"id"`
+	Version    int    `json:"version"`
+	SchemaType string `json:"schemaType"`
+	Schema     string `json:"schema"`
+}
+
+// schemaRequest is the body of the requests registering, looking up or checking a schema
+type schemaRequest struct {
+	Schema     string            `json:"schema"`
+	SchemaType string            `json:"schemaType"`
+	References []json.RawMessage `json:"references"`
+}
+
+// Registry is an in memory schema registry serving the REST API of the Confluent schema registry. Only the AVRO
+// schemas are checked for compatibility, the PROTOBUF and JSON schemas are always compatible
+type Registry struct {
+	mu            sync.Mutex
+	schemas       []schema         // schemas by id - 1
+	ids           map[string]int   // ids of the schemas by type and normalized schema
+	subjects      map[string][]int // subjects are the ids of the versions of each subject
+	compatibility string
+	configs       map[string]string // configs are the compatibility levels by subject
+	mux           *http.ServeMux
+}
+
+// New returns an empty registry with the BACKWARD compatibility level
+func New() *Registry {
+	r := &Registry{
+		ids:           make(map[string]int),
+		subjects:      make(map[string][]int),
+		compatibility: Backward,
+		configs:       make(map[string]string),
+		mux:           http.NewServeMux(),
+	}
+
+	r.mux.HandleFunc("GET /schemas/types", r.handle(r.schemaTypes))
+	r.mux.HandleFunc("GET /schemas/ids/{id}", r.handle(r.schemaByID))
+	r.mux.HandleFunc("GET /subjects", r.handle(r.subjectList))
+	r.mux.HandleFunc("POST /subjects/{subject}", r.handle(r.lookup))
+	r.mux.HandleFunc("DELETE /subjects/{subject}", r.handle(r.deleteSubject))
+	r.mux.HandleFunc("GET /subjects/{subject}/versions", r.handle(r.versionList))
+	r.mux.HandleFunc("POST /subjects/{subject}/versions", r.handle(r.register))
+	r.mux.HandleFunc("GET /subjects/{subject}/versions/{version}", r.handle(r.version))
+	r.mux.HandleFunc("POST /compatibility/subjects/{subject}/versions/{version}", r.handle(r.checkCompatibility))
+	r.mux.HandleFunc("GET /config", r.handle(r.config))
+	r.mux.HandleFunc("PUT /config", r.handle(r.updateConfig))
+	r.mux.HandleFunc("GET /config/{subject}", r.handle(r.config))
+	r.mux.HandleFunc("PUT /config/{subject}", r.handle(r.updateConfig))
+	return r
+}
+
+// ServeHTTP serves the REST API of the registry
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mux.ServeHTTP(w, req)
+}
+
+// handle returns the handler writing the response of the endpoint as JSON, or its error
+func (r *Registry) handle(endpoint func(req *http.Request) (any, error)) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		r.mu.Lock()
+		response, err := endpoint(req)
+		r.mu.Unlock()
+
+		w.Header().Set("Content-Type", contentType)
+		var apiErr *apiError
+		switch {
+		case errors.As(err, &apiErr):
+			w.WriteHeader(apiErr.status)
+			response = apiErr
+		case err != nil:
+			w.WriteHeader(http.StatusInternalServerError)
+			response = &apiError{Code: 50001, Message: err.Error()}
+		}
+		_ = json.NewEncoder(w).Encode(response)
+	}
+}
+
+// schemaTypes returns the supported schema types
+func (r *Registry) schemaTypes(*http.Request) (any, error) {
+	return []string{TypeAvro, TypeJSON, TypeProtobuf}, nil
+}
+
+// schemaByID returns the schema with the id
+func (r *Registry) schemaByID(req *http.Request) (any, error) {
+	id, err := strconv.Atoi(req.PathValue("id"))
+	if err != nil || id < 1 || id > len(r.schemas) {
+		return nil, &apiError{status: http.StatusNotFound, Code: codeSchemaNotFound, Message: "Schema not found"}
+	}
+
+	found := r.schemas[id-1]
+	return map[string]any{"schema": found.Schema, "schemaType": found.SchemaType}, nil
+}
+
+// subjectList returns the names of the subjects
+func (r *Registry) subjectList(*http.Request) (any, error) {
+	subjects := make([]string, 0, len(r.subjects))
+	for subject := range r.subjects {
+		subjects = append(subjects, subject)
+	}
+	slices.Sort(subjects)
+	return subjects, nil
+}
+
+// versionList returns the versions of the subject
+func (r *Registry) versionList(req *http.Request) (any, error) {
+	ids, err := r.subject(req.PathValue("subject"))
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]int, 0, len(ids))
+	for i := range ids {
+		versions = append(versions, i+1)
+	}
+	return versions, nil
+}
+
+// version returns the version of the subject, latest or -1 for the last one
+func (r *Registry) version(req *http.Request) (any, error) {
+	subject := req.PathValue("subject")
+	ids, err := r.subject(subject)
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := versionOf(req.PathValue("version"), len(ids))
+	if err != nil {
+		return nil, err
+	}
+	return r.subjectVersion(subject, version), nil
+}
+
+// register registers the schema under the subject and returns its id. The id of the schema is returned if it is
+// already registered under the subject, a new version is created if it is compatible with the previous ones
+func (r *Registry) register(req *http.Request) (any, error) {
+	subject := req.PathValue("subject")
+	request, normalized, err := decodeSchema(req)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := r.subjects[subject]
+	key := request.SchemaType + "\x00" + normalized
+	if id, ok := r.ids[key]; ok && slices.Contains(ids, id) {
+		return map[string]int{"id": id}, nil
+	}
+
+	if problems := r.incompatibilities(subject, request, len(ids)); len(problems) > 0 {
+		return nil, &apiError{
+			status:  http.StatusConflict,
+			Code:    codeIncompatibleSchema,
+			Message: "Schema being registered is incompatible with an earlier schema: " + strings.Join(problems, "; "),
+		}
+	}
+
+	id, ok := r.ids[key]
+	if !ok {
+		r.schemas = append(r.schemas, schema{ID: len(r.schemas) + 1, SchemaType: request.SchemaType, Schema: request.Schema})
+		id = len(r.schemas)
+		r.ids[key] = id
+	}
+	r.subjects[subject] = append(ids, id)
+	return map[string]int{"id": id}, nil
+}
+
+// lookup returns the version of the subject with the schema
+func (r *Registry) lookup(req *http.Request) (any, error) {
+	subject := req.PathValue("subject")
+	ids, err := r.subject(subject)
+	if err != nil {
+		return nil, err
+	}
+
+	request, normalized, err := decodeSchema(req)
+	if err != nil {
+		return nil, err
+	}
+
+	id, ok := r.ids[request.SchemaType+"\x00"+normalized]
+	version := slices.Index(ids, id)
+	if !ok || version < 0 {
+		return nil, &apiError{status: http.StatusNotFound, Code: codeSchemaNotFound, Message: "Schema not found"}
+	}
+	return r.subjectVersion(subject, version+1), nil
+}
+
+// deleteSubject deletes the subject and returns its versions, the schemas stay available by id
+func (r *Registry) deleteSubject(req *http.Request) (any, error) {
+	subject := req.PathValue("subject")
+	versions, err := r.versionList(req)
+	if err != nil {
+		return nil, err
+	}
+
+	delete(r.subjects, subject)
+	delete(r.configs, subject)
+	return versions, nil
+}
+
+// checkCompatibility returns whether the schema is compatible with the version of the subject
+func (r *Registry) checkCompatibility(req *http.Request) (any, error) {
+	subject := req.PathValue("subject")
+	ids, err := r.subject(subject)
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := versionOf(req.PathValue("version"), len(ids))
+	if err != nil {
+		return nil, err
+	}
+
+	request, _, err := decodeSchema(req)
+	if err != nil {
+		return nil, err
+	}
+
+	problems := r.incompatibilities(subject, request, version)
+	return map[string]any{"is_compatible": len(problems) == 0, "messages": problems}, nil
+}
+
+// config returns the compatibility level of the subject, or the global one
+func (r *Registry) config(req *http.Request) (any, error) {
+	subject := req.PathValue("subject")
+	if level, ok := r.configs[subject]; ok {
+		return map[string]string{"compatibilityLevel": level}, nil
+	}
+
+	if subject != "" && req.URL.Query().Get("defaultToGlobal") != "true" {
+		return nil, &apiError{status: http.StatusNotFound, Code: codeSubjectNotFound, Message: "Subject not found"}
+	}
+	return map[string]string{"compatibilityLevel": r.compatibility}, nil
+}
+
+// updateConfig sets the compatibility level of the subject, or the global one
+func (r *Registry) updateConfig(req *http.Request) (any, error) {
+	var request struct {
+		Compatibility string `json:"compatibility"`
+	}
+
+	if err := json.NewDecoder(req.Body).Decode(&request); err != nil || !slices.Contains(compatibilityLevels, request.Compatibility) {
+		return nil, &apiError{
+			status:  http.StatusUnprocessableEntity,
+			Code:    codeInvalidCompatibility,
+			Message: fmt.Sprintf("Invalid compatibility level, expected one of %s", strings.Join(compatibilityLevels, ", ")),
+		}
+	}
+
+	if subject := req.PathValue("subject"); subject != "" {
+		r.configs[subject] = request.Compatibility
+	} else {
+		r.compatibility = request.Compatibility
+	}
+	return request, nil
+}
+
+// subject returns the ids of the versions of the subject
+func (r *Registry) subject(subject string) ([]int, error) {
+	ids, ok := r.subjects[subject]
+	if !ok {
+		return nil, &apiError{status: http.StatusNotFound, Code: codeSubjectNotFound, Message: fmt.Sprintf("Subject '%s' not found.", subject)}
+	}
+	return ids, nil
+}
+
+// subjectVersion returns the version of the subject, the version starts at 1
+func (r *Registry) subjectVersion(subject string, version int) subjectVersion {
+	found := r.schemas[r.subjects[subject][version-1]-1]
+	return subjectVersion{
+		Subject:    subject,
+		ID:         found.ID,
+		Version:    version,
+		SchemaType: found.SchemaType,
+		Schema:     found.Schema,
+	}
+}
+
+// incompatibilities returns the reasons the schema is not compatible with the versions of the subject up to the
+// version, following the compatibility level of the subject
+func (r *Registry) incompatibilities(subject string, request schemaRequest, version int) []string {
+	level, ok := r.configs[subject]
+	if !ok {
+		level = r.compatibility
+	}
+
+	ids := r.subjects[subject][:version]
+	if level == None || len(ids) == 0 {
+		return nil
+	}
+
+	if !strings.HasSuffix(level, "_TRANSITIVE") {
+		ids = ids[len(ids)-1:]
+	}
+
+	problems := make([]string, 0)
+	for _, id := range ids {
+		previous := r.schemas[id-1]
+		if previous.SchemaType != request.SchemaType {
+			problems = append(problems, fmt.Sprintf("schema type %s differs from %s of schema %d", request.SchemaType, previous.SchemaType, id))
+			continue
+		}
+
+		if request.SchemaType != TypeAvro {
+			continue
+		}
+
+		// schemas are valid once registered, the requests are validated when decoded
+		newSchema, _ := parseAvro(request.Schema)
+		oldSchema, _ := parseAvro(previous.Schema)
+		compatibility := avro.NewSchemaCompatibility()
+		if level != Forward && level != ForwardTransitive {
+			if err := compatibility.Compatible(newSchema, oldSchema); err != nil {
+				problems = append(problems, fmt.Sprintf("cannot read the data of schema %d: %v", id, err))
+			}
+		}
+
+		if level != Backward && level != BackwardTransitive {
+			if err := compatibility.Compatible(oldSchema, newSchema); err != nil {
+				problems = append(problems, fmt.Sprintf("schema %d cannot read the data: %v", id, err))
+			}
+		}
+	}
+	return problems
+}
+
+// decodeSchema returns the schema of the request with its normalized form, AVRO by default
+func decodeSchema(req *http.Request) (schemaRequest, string, error) {
+	var request schemaRequest
+	if err := json.NewDecoder(req.Body).Decode(&request); err != nil {
+		return request, "", invalidSchema(errors.Wrap(err, "invalid request"))
+	}
+
+	if request.SchemaType == "" {
+		request.SchemaType = TypeAvro
+	}
+
+	if len(request.References) > 0 {
+		return request, "", invalidSchema(errors.New("schema references are not supported"))
+	}
+
+	switch request.SchemaType {
+	case TypeAvro:
+		parsed, err := parseAvro(request.Schema)
+		if err != nil {
+			return request, "", invalidSchema(err)
+		}
+		return request, parsed.String(), nil
+	case TypeJSON:
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, []byte(request.Schema)); err != nil {
+			return request, "", invalidSchema(err)
+		}
+		return request, compact.String(), nil
+	case TypeProtobuf:
+		if strings.TrimSpace(request.Schema) == "" {
+			return request, "", invalidSchema(errors.New("empty schema"))
+		}
+		return request, strings.TrimSpace(request.Schema), nil
+	default:
+		return request, "", invalidSchema(errors.Errorf("unknown schema type %s", request.SchemaType))
+	}
+}
+
+// parseAvro parses the avro schema with a cache of its own, the versions of a schema share the same names
+func parseAvro(schema string) (avro.Schema, error) {
+	return avro.ParseWithCache(schema, "", &avro.SchemaCache{})
+}
+
+// invalidSchema returns the error response of an invalid schema
+func invalidSchema(err error) error {
+	return &apiError{status: http.StatusUnprocessableEntity, Code: codeInvalidSchema, Message: "Invalid schema: " + err.Error()}
+}
+
+// versionOf returns the version of the path, latest and -1 are the last version
+func versionOf(value string, count int) (int, error) {
+	if value == "latest" || value == "-1" {
+		return count, nil
+	}
+
+	version, err := strconv.Atoi(value)
+	if err != nil || version < 1 {
+		return 0, &apiError{status: http.StatusUnprocessableEntity, Code: codeInvalidVersion, Message: fmt.Sprintf("The specified version '%s' is not a valid version id.", value)}
+	}
+
+	if version > count {
+		return 0, &apiError{status: http.StatusNotFound, Code: codeVersionNotFound, Message: "Version not found."}
+	}
+	return version, nil
+}
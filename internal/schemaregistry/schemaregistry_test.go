@@ -0,0 +1,78 @@
+package schemaregistry_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bdpiprava/testkit/internal/schemaregistry"
+)
+
+const (
+	orderV1 = `{"type":"record","name":"Order","fields":[{"name":"id","type":"string"}]}`
+	// orderV2 adds a field with a default, backward compatible with orderV1
+	orderV2 = `{"type":"record","name":"Order","fields":[{"name":"id","type":"string"},{"name":"status","type":"string","default":"NEW"}]}`
+	// orderV3 adds a field without a default, not backward compatible with orderV2
+	orderV3 = `{"type":"record","name":"Order","fields":[{"name":"id","type":"string"},{"name":"status","type":"string","default":"NEW"},{"name":"total","type":"double"}]}`
+)
+
+func Test_Registry(t *testing.T) {
+	server := httptest.NewServer(schemaregistry.New())
+	t.Cleanup(server.Close)
+
+	steps := []struct {
+		name       string
+		method     string
+		path       string
+		body       any
+		wantStatus int
+		want       string
+	}{
+		{name: "register a schema", method: http.MethodPost, path: "/subjects/orders-value/versions", body: schemaBody(orderV1), wantStatus: http.StatusOK, want: `{"id":1}`},
+		{name: "register the same schema", method: http.MethodPost, path: "/subjects/orders-value/versions", body: schemaBody(orderV1), wantStatus: http.StatusOK, want: `{"id":1}`},
+		{name: "register a compatible schema", method: http.MethodPost, path: "/subjects/orders-value/versions", body: schemaBody(orderV2), wantStatus: http.StatusOK, want: `{"id":2}`},
+		{name: "check an incompatible schema", method: http.MethodPost, path: "/compatibility/subjects/orders-value/versions/latest", body: schemaBody(orderV3), wantStatus: http.StatusOK, want: `"is_compatible":false`},
+		{name: "reject an incompatible schema", method: http.MethodPost, path: "/subjects/orders-value/versions", body: schemaBody(orderV3), wantStatus: http.StatusConflict, want: `"error_code":409`},
+		{name: "register the schema under another subject", method: http.MethodPost, path: "/subjects/payments-value/versions", body: schemaBody(orderV1), wantStatus: http.StatusOK, want: `{"id":1}`},
+		{name: "list the subjects", method: http.MethodGet, path: "/subjects", wantStatus: http.StatusOK, want: `["orders-value","payments-value"]`},
+		{name: "list the versions", method: http.MethodGet, path: "/subjects/orders-value/versions", wantStatus: http.StatusOK, want: `[1,2]`},
+		{name: "get the latest version", method: http.MethodGet, path: "/subjects/orders-value/versions/latest", wantStatus: http.StatusOK, want: `"id":2,"version":2`},
+		{name: "get a missing version", method: http.MethodGet, path: "/subjects/orders-value/versions/3", wantStatus: http.StatusNotFound, want: `"error_code":40402`},
+		{name: "get the schema by id", method: http.MethodGet, path: "/schemas/ids/2", wantStatus: http.StatusOK, want: `"schemaType":"AVRO"`},
+		{name: "get a missing schema", method: http.MethodGet, path: "/schemas/ids/3", wantStatus: http.StatusNotFound, want: `"error_code":40403`},
+		{name: "look up the schema", method: http.MethodPost, path: "/subjects/orders-value", body: schemaBody(orderV1), wantStatus: http.StatusOK, want: `"id":1,"version":1`},
+		{name: "reject an invalid schema", method: http.MethodPost, path: "/subjects/orders-value/versions", body: schemaBody(`{"type":"record"}`), wantStatus: http.StatusUnprocessableEntity, want: `"error_code":42201`},
+		{name: "disable the compatibility checks", method: http.MethodPut, path: "/config/orders-value", body: map[string]string{"compatibility": "NONE"}, wantStatus: http.StatusOK, want: `{"compatibility":"NONE"}`},
+		{name: "register the incompatible schema", method: http.MethodPost, path: "/subjects/orders-value/versions", body: schemaBody(orderV3), wantStatus: http.StatusOK, want: `{"id":3}`},
+		{name: "get the global config", method: http.MethodGet, path: "/config", wantStatus: http.StatusOK, want: `{"compatibilityLevel":"BACKWARD"}`},
+		{name: "delete the subject", method: http.MethodDelete, path: "/subjects/orders-value", wantStatus: http.StatusOK, want: `[1,2,3]`},
+		{name: "get a deleted subject", method: http.MethodGet, path: "/subjects/orders-value/versions", wantStatus: http.StatusNotFound, want: `"error_code":40401`},
+		{name: "get the schema of a deleted subject", method: http.MethodGet, path: "/schemas/ids/3", wantStatus: http.StatusOK, want: `"schema"`},
+	}
+
+	for _, step := range steps {
+		content, err := json.Marshal(step.body)
+		require.NoError(t, err)
+
+		req, err := http.NewRequest(step.method, server.URL+step.path, strings.NewReader(string(content)))
+		require.NoError(t, err)
+
+		res, err := http.DefaultClient.Do(req)
+		require.NoError(t, err, step.name)
+		body, err := io.ReadAll(res.Body)
+		_ = res.Body.Close()
+		require.NoError(t, err)
+
+		require.Equal(t, step.wantStatus, res.StatusCode, "%s: %s", step.name, body)
+		require.Contains(t, string(body), step.want, step.name)
+	}
+}
+
+func schemaBody(schema string) map[string]string {
+	return map[string]string{"schema": schema}
+}
@@ -1,18 +1,27 @@
 package internal
 
+import (
+	"maps"
+	"time"
+)
+
 // SuiteConfig is the configuration for the test suite
 type SuiteConfig struct {
-	LogLevel        string               `yaml:"log_level"`     // LogLevel is the log level
-	PostgresConfig  PostgresConfig       `yaml:"postgres"`      // PostgresConfig configuration for the postgres database
-	ElasticSearch   *ElasticSearchConfig `yaml:"elasticsearch"` // ElasticSearchConfig configuration for the elastic search client
-	OpenSearch      *ElasticSearchConfig `yaml:"opensearch"`    // OpenSearch configuration for the elastic search client
-	GoMigrateConfig *GoMigrateConfig     `yaml:"go-migrate"`    // GoMigrateConfig config for go migrate
-	APIMockConfig   *APIMockConfig       `yaml:"api-mock"`      // APIMockConfig configuration for the API mock
+	LogLevel        string               `yaml:"log_level,omitempty" default:"info"` // LogLevel is the log level
+	TestTimeout     time.Duration        `yaml:"test_timeout,omitempty"`             // TestTimeout is the maximum duration of each test, zero disables it
+	Retries         int                  `yaml:"retries,omitempty"`                  // Retries is the number of times a failed test method is retried
+	PostgresConfig  PostgresConfig       `yaml:"postgres,omitempty"`                 // PostgresConfig configuration for the postgres database
+	ElasticSearch   *ElasticSearchConfig `yaml:"elasticsearch,omitempty"`            // ElasticSearchConfig configuration for the elastic search client
+	OpenSearch      *ElasticSearchConfig `yaml:"opensearch,omitempty"`               // OpenSearch configuration for the elastic search client
+	GoMigrateConfig *GoMigrateConfig     `yaml:"go-migrate,omitempty"`               // GoMigrateConfig config for go migrate
+	APIMockConfig   *APIMockConfig       `yaml:"api-mock,omitempty"`                 // APIMockConfig configuration for the API mock
+	Kafka           *KafkaConfig         `yaml:"kafka,omitempty"`                    // Kafka configuration of the mock cluster or the external broker
+	Readiness       ReadinessConfig      `yaml:"readiness,omitempty"`                // Readiness configuration of the wait for the backends to be ready
 }
 
 // PostgresConfig is the configuration for the postgres database provider
 type PostgresConfig struct {
-	Database     string            `yaml:"name"`          // Database of the database
+	Database     string            `yaml:"database"`      // Database of the database
 	User         string            `yaml:"user"`          // User of the database
 	Password     string            `yaml:"password"`      // Password of the database
 	Host         string            `yaml:"host"`          // Host of the database e.g. localhost:5432
@@ -37,5 +46,130 @@ type GoMigrateConfig struct {
 
 // APIMockConfig is the configuration for the API mock
 type APIMockConfig struct {
-	Address string `yaml:"address" default:"localhost:8080"`
+	Address string `yaml:"address" default:"http://localhost:8080"`
+}
+
+const (
+	KafkaModeMock   = "mock"   // KafkaModeMock creates an in process mock cluster for each test
+	KafkaModeBroker = "broker" // KafkaModeBroker uses the external broker, the topics of each test are prefixed
+)
+
+// KafkaConfig is the configuration of the kafka clusters of the tests
+type KafkaConfig struct {
+	Mode              string            `yaml:"mode" default:"mock"`            // Mode is mock or broker
+	BootstrapServers  string            `yaml:"bootstrap_servers"`              // BootstrapServers of the broker, comma separated
+	SecurityProtocol  string            `yaml:"security_protocol"`              // SecurityProtocol e.g. SASL_SSL
+	SASL              *KafkaSASLConfig  `yaml:"sasl"`                           // SASL authentication to the broker
+	TLS               *KafkaTLSConfig   `yaml:"tls"`                            // TLS connection to the broker
+	Client            map[string]string `yaml:"client"`                         // Client are librdkafka properties overriding the ones of the clients
+	Partitions        int               `yaml:"partitions" default:"1"`         // Partitions of the topics created for the tests
+	ReplicationFactor int               `yaml:"replication_factor" default:"1"` // ReplicationFactor of the topics created for the tests
+	KeepTopics        bool              `yaml:"keep_topics"`                    // KeepTopics disables the deletion of the topics when the test completes
+}
+
+// KafkaSASLConfig is the SASL authentication to the kafka broker
+type KafkaSASLConfig struct {
+	Mechanism string `yaml:"mechanism" default:"PLAIN"` // Mechanism e.g. PLAIN or SCRAM-SHA-512
+	Username  string `yaml:"username"`
+	Password  string `yaml:"password"`
+}
+
+// KafkaTLSConfig is the TLS connection to the kafka broker
+type KafkaTLSConfig struct {
+	CALocation          string `yaml:"ca_location"`          // CALocation is the path of the CA certificate
+	CertificateLocation string `yaml:"certificate_location"` // CertificateLocation is the path of the client certificate
+	KeyLocation         string `yaml:"key_location"`         // KeyLocation is the path of the client key
+	KeyPassword         string `yaml:"key_password"`         // KeyPassword is the password of the client key
+	SkipVerify          bool   `yaml:"skip_verify"`          // SkipVerify disables the verification of the broker certificate
+}
+
+// ClientConfig returns the librdkafka properties to connect to the broker, the client overrides included
+func (c KafkaConfig) ClientConfig() map[string]string {
+	config := map[string]string{"bootstrap.servers": c.BootstrapServers}
+	if c.SecurityProtocol != "" {
+		config["security.protocol"] = c.SecurityProtocol
+	}
+
+	if c.SASL != nil {
+		config["sasl.mechanism"] = c.SASL.Mechanism
+		config["sasl.username"] = c.SASL.Username
+		config["sasl.password"] = c.SASL.Password
+	}
+
+	if c.TLS != nil {
+		for key, value := range map[string]string{
+			"ssl.ca.location":          c.TLS.CALocation,
+			"ssl.certificate.location": c.TLS.CertificateLocation,
+			"ssl.key.location":         c.TLS.KeyLocation,
+			"ssl.key.password":         c.TLS.KeyPassword,
+		} {
+			if value != "" {
+				config[key] = value
+			}
+		}
+
+		if c.TLS.SkipVerify {
+			config["enable.ssl.certificate.verification"] = "false"
+		}
+	}
+
+	maps.Copy(config, c.Client)
+	return config
+}
+
+// BackoffConfig is the configuration of the wait for a backend to be ready
+type BackoffConfig struct {
+	Timeout     time.Duration `yaml:"timeout,omitempty" default:"30s"`     // Timeout is the maximum duration to wait for the backend
+	Interval    time.Duration `yaml:"interval,omitempty" default:"500ms"`  // Interval is the delay after the first failed check, doubled after each one
+	MaxInterval time.Duration `yaml:"max_interval,omitempty" default:"5s"` // MaxInterval is the maximum delay between two checks
+}
+
+// ReadinessConfig is the configuration of the wait for the configured backends to be ready before the suites run
+type ReadinessConfig struct {
+	BackoffConfig `yaml:",inline"`
+	Backends      map[string]BackoffConfig `yaml:"backends,omitempty"` // Backends override the backoff by backend e.g. postgres
+}
+
+// For returns the backoff of the backend, the fields set for the backend override the default ones
+func (c ReadinessConfig) For(backend string) BackoffConfig {
+	backoff := c.BackoffConfig
+	override := c.Backends[backend]
+	if override.Timeout != 0 {
+		backoff.Timeout = override.Timeout
+	}
+	if override.Interval != 0 {
+		backoff.Interval = override.Interval
+	}
+	if override.MaxInterval != 0 {
+		backoff.MaxInterval = override.MaxInterval
+	}
+	return backoff
+}
+
+// Clone returns a deep copy of the config
+func (c SuiteConfig) Clone() SuiteConfig {
+	clone := c
+	clone.PostgresConfig.QueryParams = maps.Clone(c.PostgresConfig.QueryParams)
+	clone.ElasticSearch = clonePointer(c.ElasticSearch)
+	clone.OpenSearch = clonePointer(c.OpenSearch)
+	clone.GoMigrateConfig = clonePointer(c.GoMigrateConfig)
+	clone.APIMockConfig = clonePointer(c.APIMockConfig)
+	clone.Readiness.Backends = maps.Clone(c.Readiness.Backends)
+	if c.Kafka != nil {
+		kafka := *c.Kafka
+		kafka.SASL = clonePointer(c.Kafka.SASL)
+		kafka.TLS = clonePointer(c.Kafka.TLS)
+		kafka.Client = maps.Clone(c.Kafka.Client)
+		clone.Kafka = &kafka
+	}
+	return clone
+}
+
+// clonePointer returns a pointer to a copy of the value, nil if the pointer is nil
+func clonePointer[T any](value *T) *T {
+	if value == nil {
+		return nil
+	}
+	clone := *value
+	return &clone
 }
@@ -0,0 +1,203 @@
+package testkit
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+)
+
+// KafkaLag is the lag of a consumer group by topic and partition, the number of messages not consumed yet
+type KafkaLag map[string]map[int32]int64
+
+// Total returns the lag of the group on all the partitions
+func (l KafkaLag) Total() int64 {
+	var total int64
+	for _, partitions := range l {
+		for _, lag := range partitions {
+			total += lag
+		}
+	}
+	return total
+}
+
+// String returns the lag as a table of the topics and partitions
+func (l KafkaLag) String() string {
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "TOPIC\tPARTITION\tLAG")
+	for _, topic := range slices.Sorted(maps.Keys(l)) {
+		for _, partition := range slices.Sorted(maps.Keys(l[topic])) {
+			_, _ = fmt.Fprintf(w, "%s\t%d\t%d\n", topic, partition, l[topic][partition])
+		}
+	}
+	_ = w.Flush()
+	return sb.String()
+}
+
+// KafkaTopics returns the names of the topics of the kafka cluster of the test, the topics of the test without their
+// prefix on the external broker
+func (s *Suite) KafkaTopics() []string {
+	cluster := s.getCluster()
+	metadata := s.kafkaMetadata(nil)
+
+	topics := make([]string, 0, len(metadata.Topics))
+	for name := range metadata.Topics {
+		if topic, ok := strings.CutPrefix(name, cluster.prefix); ok {
+			topics = append(topics, topic)
+		}
+	}
+	slices.Sort(topics)
+	return topics
+}
+
+// DescribeKafkaTopic returns the partitions of the topic with their leader, replicas and in sync replicas
+func (s *Suite) DescribeKafkaTopic(topic string) kafka.TopicMetadata {
+	cluster := s.getCluster()
+	name := cluster.topic(topic)
+	metadata := s.kafkaMetadata(&name)
+
+	described, ok := metadata.Topics[name]
+	s.Require().True(ok, "topic %s not found", topic)
+	s.Require().NoError(asError(described.Error), "failed to describe topic %s", topic)
+	described.Topic = topic
+	return described
+}
+
+// KafkaCommittedOffsets returns the offsets committed by the consumer group on the partitions of the topic, the
+// partitions without committed offset are missing
+func (s *Suite) KafkaCommittedOffsets(group, topic string) map[int32]kafka.Offset {
+	committed := s.committedOffsets(group, []string{topic})
+	return committed[topic]
+}
+
+// KafkaConsumerLag returns the lag of the consumer group on the partitions of the topics, the difference between the
+// high watermark and the committed offset. Without topics, the lag is computed on the topics of the test the group
+// committed offsets on. The partitions without committed offset lag from their low watermark
+func (s *Suite) KafkaConsumerLag(group string, topics ...string) KafkaLag {
+	if len(topics) == 0 {
+		topics = slices.Collect(maps.Keys(s.committedOffsets(group, s.KafkaTopics())))
+	}
+
+	producer := s.getProducers().producer
+	cluster := s.getCluster()
+	committed := s.committedOffsets(group, topics)
+	lag := make(KafkaLag, len(topics))
+	for _, topic := range topics {
+		lag[topic] = make(map[int32]int64)
+		for _, partition := range s.DescribeKafkaTopic(topic).Partitions {
+			low, high, err := producer.QueryWatermarkOffsets(cluster.topic(topic), partition.ID, int(deliveryTimeout.Milliseconds()))
+			s.Require().NoError(err, "failed to query the watermarks of %s[%d]", topic, partition.ID)
+
+			offset, ok := committed[topic][partition.ID]
+			if !ok {
+				offset = kafka.Offset(low)
+			}
+			lag[topic][partition.ID] = max(high-int64(offset), 0)
+		}
+	}
+	return lag
+}
+
+// AssertGroupCaughtUp asserts the consumer group consumes all the messages of the topics within the timeout, the lag
+// is checked until it is zero. Without topics, the topics of the test the group committed offsets on are checked
+func (s *Suite) AssertGroupCaughtUp(group string, timeout time.Duration, topics ...string) bool {
+	s.T().Helper()
+	deadline := time.Now().Add(timeout)
+	for {
+		lag := s.KafkaConsumerLag(group, topics...)
+		if len(lag) > 0 && lag.Total() == 0 {
+			return true
+		}
+
+		if time.Now().After(deadline) {
+			if len(lag) == 0 {
+				return s.Fail(fmt.Sprintf("consumer group %s has not committed offsets within %s", group, timeout))
+			}
+			return s.Fail(fmt.Sprintf("consumer group %s has not caught up within %s, lag %d:\n%s", group, timeout, lag.Total(), lag))
+		}
+
+		select {
+		case <-s.GetContext().Done():
+			return s.Fail(fmt.Sprintf("context done while waiting for consumer group %s: %v", group, context.Cause(s.GetContext())))
+		case <-time.After(pollTimeout):
+		}
+	}
+}
+
+// DescribeGroup returns the state, the members and their assignments of the consumer group. The mock cluster does
+// not support the description of the groups, it requires the external broker
+func (s *Suite) DescribeGroup(group string) kafka.ConsumerGroupDescription {
+	admin, err := s.getCluster().adminClient()
+	s.Require().NoError(err)
+
+	ctx, cancel := context.WithTimeout(s.GetContext(), deliveryTimeout)
+	defer cancel()
+	result, err := admin.DescribeConsumerGroups(ctx, []string{group})
+	s.Require().NoError(err, "failed to describe consumer group %s", group)
+	s.Require().Len(result.ConsumerGroupDescriptions, 1)
+
+	described := result.ConsumerGroupDescriptions[0]
+	s.Require().NoError(asError(described.Error), "failed to describe consumer group %s", group)
+	return described
+}
+
+// committedOffsets returns the offsets committed by the consumer group on the partitions of the topics by topic
+func (s *Suite) committedOffsets(group string, topics []string) map[string]map[int32]kafka.Offset {
+	cluster := s.getCluster()
+	admin, err := cluster.adminClient()
+	s.Require().NoError(err)
+
+	partitions := make([]kafka.TopicPartition, 0)
+	for _, topic := range topics {
+		name := cluster.topic(topic)
+		for _, partition := range s.DescribeKafkaTopic(topic).Partitions {
+			partitions = append(partitions, kafka.TopicPartition{Topic: &name, Partition: partition.ID})
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(s.GetContext(), deliveryTimeout)
+	defer cancel()
+	result, err := admin.ListConsumerGroupOffsets(ctx, []kafka.ConsumerGroupTopicPartitions{{Group: group, Partitions: partitions}})
+	s.Require().NoError(err, "failed to list the offsets of consumer group %s", group)
+
+	committed := make(map[string]map[int32]kafka.Offset)
+	for _, offsets := range result.ConsumerGroupsTopicPartitions {
+		for _, partition := range offsets.Partitions {
+			s.Require().NoError(partition.Error, "failed to list the offsets of consumer group %s", group)
+			if partition.Offset < 0 {
+				continue
+			}
+
+			topic := strings.TrimPrefix(*partition.Topic, cluster.prefix)
+			if committed[topic] == nil {
+				committed[topic] = make(map[int32]kafka.Offset)
+			}
+			committed[topic][partition.Partition] = partition.Offset
+		}
+	}
+	return committed
+}
+
+// kafkaMetadata returns the metadata of the topic, or of all the topics when nil
+func (s *Suite) kafkaMetadata(topic *string) *kafka.Metadata {
+	admin, err := s.getCluster().adminClient()
+	s.Require().NoError(err)
+
+	metadata, err := admin.GetMetadata(topic, topic == nil, int(deliveryTimeout.Milliseconds()))
+	s.Require().NoError(err, "failed to get the kafka metadata")
+	return metadata
+}
+
+// asError returns the kafka error as an error, nil when it has no error code
+func asError(err kafka.Error) error {
+	if err.Code() == kafka.ErrNoError {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,251 @@
+package testkit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+
+	"github.com/bdpiprava/testkit/maps"
+)
+
+// maxNearMisses is the maximum number of near misses reported when an expectation fails
+const maxNearMisses = 5
+
+// messageMatcher is a condition on the messages of a KafkaExpectation, it returns the reason of the mismatch or an
+// empty string when the message matches
+type messageMatcher struct {
+	description string
+	mismatch    func(message *kafka.Message) string
+}
+
+// KafkaExpectation collects the messages of a topic matching all of its conditions, created by ExpectKafkaMessages
+type KafkaExpectation struct {
+	s        *Suite
+	topic    string
+	count    int
+	matchers []messageMatcher
+}
+
+// nearMiss is a message of the topic failing some conditions of an expectation
+type nearMiss struct {
+	message *kafka.Message
+	reasons []string
+}
+
+// ExpectKafkaMessages returns an expectation on the messages of the topic, one message matching the conditions is
+// expected unless Count is called. The topic is read from the beginning by a consumer group of its own
+//
+//	messages := s.ExpectKafkaMessages("orders").
+//		WithKey("order-1").
+//		WithHeader("type", "created").
+//		WithJSONValueContaining(map[string]any{"status": "NEW"}).
+//		Count(2).
+//		Within(5 * time.Second)
+func (s *Suite) ExpectKafkaMessages(topic string) *KafkaExpectation {
+	return &KafkaExpectation{s: s, topic: topic, count: 1}
+}
+
+// WithKey expects the key of the messages to be the key
+func (e *KafkaExpectation) WithKey(key string) *KafkaExpectation {
+	return e.Matching(fmt.Sprintf("key %q", key), func(message *kafka.Message) string {
+		if !bytes.Equal(message.Key, []byte(key)) {
+			return fmt.Sprintf("key is %q, expected %q", message.Key, key)
+		}
+		return ""
+	})
+}
+
+// WithHeader expects the messages to have the header with the value
+func (e *KafkaExpectation) WithHeader(key, value string) *KafkaExpectation {
+	return e.Matching(fmt.Sprintf("header %s=%q", key, value), func(message *kafka.Message) string {
+		values := make([]string, 0)
+		for _, header := range message.Headers {
+			if header.Key != key {
+				continue
+			}
+
+			if string(header.Value) == value {
+				return ""
+			}
+			values = append(values, fmt.Sprintf("%q", header.Value))
+		}
+
+		if len(values) == 0 {
+			return fmt.Sprintf("header %s is missing", key)
+		}
+		return fmt.Sprintf("header %s is %s, expected %q", key, strings.Join(values, ", "), value)
+	})
+}
+
+// WithJSONValueContaining expects the value of the messages to be a JSON object containing the fields of expected,
+// the nested objects are compared the same way
+func (e *KafkaExpectation) WithJSONValueContaining(expected map[string]any) *KafkaExpectation {
+	// the round trip makes the types of expected the ones of the decoded values e.g. float64 for the numbers
+	var subset map[string]any
+	content, err := json.Marshal(expected)
+	if err == nil {
+		err = json.Unmarshal(content, &subset)
+	}
+	e.s.Require().NoError(err, "expected value is not a valid JSON object")
+
+	return e.Matching(fmt.Sprintf("JSON value containing %s", content), func(message *kafka.Message) string {
+		var actual map[string]any
+		if err := json.Unmarshal(message.Value, &actual); err != nil {
+			return fmt.Sprintf("value is not a JSON object: %v", err)
+		}
+
+		if ok, reason := maps.ContainsWithReason(actual, subset); !ok {
+			return fmt.Sprintf("value does not contain the expected fields: %s", reason)
+		}
+		return ""
+	})
+}
+
+// Matching expects the messages to match the custom condition, mismatch returns the reason the message does not
+// match or an empty string when it matches
+func (e *KafkaExpectation) Matching(description string, mismatch func(message *kafka.Message) string) *KafkaExpectation {
+	e.matchers = append(e.matchers, messageMatcher{description: description, mismatch: mismatch})
+	return e
+}
+
+// Count expects n messages matching the conditions
+func (e *KafkaExpectation) Count(n int) *KafkaExpectation {
+	e.count = n
+	return e
+}
+
+// Within waits up to the timeout for the expected number of matching messages and returns the messages collected.
+// The test fails when fewer messages are found, reporting the conditions and the messages which did not match them
+func (e *KafkaExpectation) Within(timeout time.Duration) []*kafka.Message {
+	e.s.T().Helper()
+	timeoutTimer := time.NewTimer(timeout)
+	defer timeoutTimer.Stop()
+
+	var collected sync.Mutex
+	var matched []*kafka.Message
+	var misses []nearMiss
+	done := make(chan struct{})
+	stop := e.s.consume([]string{e.topic}, e.s.isolatedKafkaConfig("expect"), func(message *kafka.Message) bool {
+		collected.Lock()
+		defer collected.Unlock()
+		if reasons := e.mismatches(message); len(reasons) > 0 {
+			misses = append(misses, nearMiss{message: message, reasons: reasons})
+			return false
+		}
+
+		matched = append(matched, message)
+		if len(matched) < e.count {
+			return false
+		}
+		close(done)
+		return true
+	}, nil)
+	defer stop()
+
+	ctx := e.s.GetContext()
+	select {
+	case <-done:
+	case <-ctx.Done():
+	case <-timeoutTimer.C:
+	}
+
+	collected.Lock()
+	defer collected.Unlock()
+	if len(matched) < e.count {
+		e.s.Fail(e.failure(len(matched), timeout, misses))
+	}
+	return matched
+}
+
+// mismatches returns the reasons the message does not match the conditions of the expectation
+func (e *KafkaExpectation) mismatches(message *kafka.Message) []string {
+	reasons := make([]string, 0)
+	for _, matcher := range e.matchers {
+		if reason := matcher.mismatch(message); reason != "" {
+			reasons = append(reasons, reason)
+		}
+	}
+	return reasons
+}
+
+// failure returns the message of a failed expectation with its near misses, the messages failing the fewest
+// conditions first
+func (e *KafkaExpectation) failure(found int, timeout time.Duration, misses []nearMiss) string {
+	descriptions := make([]string, 0, len(e.matchers))
+	for _, matcher := range e.matchers {
+		descriptions = append(descriptions, matcher.description)
+	}
+
+	conditions := "any message"
+	if len(descriptions) > 0 {
+		conditions = strings.Join(descriptions, ", ")
+	}
+
+	var sb strings.Builder
+	_, _ = fmt.Fprintf(&sb, "expected %d messages in topic %s matching %s within %s, found %d", e.count, e.topic, conditions, timeout, found)
+	if len(misses) == 0 {
+		return sb.String()
+	}
+
+	_, _ = fmt.Fprintf(&sb, "\nnear misses (%d messages did not match):", len(misses))
+	for _, miss := range nearestMisses(misses) {
+		_, _ = fmt.Fprintf(&sb, "\n  - partition %d offset %s key %q:", miss.message.TopicPartition.Partition, miss.message.TopicPartition.Offset, miss.message.Key)
+		for _, reason := range miss.reasons {
+			_, _ = fmt.Fprintf(&sb, "\n      %s", strings.ReplaceAll(reason, "\n", "\n      "))
+		}
+	}
+	return sb.String()
+}
+
+// nearestMisses returns up to maxNearMisses misses failing the fewest conditions, in the order they were consumed
+func nearestMisses(misses []nearMiss) []nearMiss {
+	nearest := make([]nearMiss, 0, maxNearMisses)
+	for failed := 1; len(nearest) < maxNearMisses && len(nearest) < len(misses); failed++ {
+		for _, miss := range misses {
+			if len(miss.reasons) == failed && len(nearest) < maxNearMisses {
+				nearest = append(nearest, miss)
+			}
+		}
+	}
+	return nearest
+}
+
+// AssertNoKafkaMessage asserts no message is produced to the topic within the duration. The topic is read from the
+// beginning by a consumer group of its own, the messages produced before the call fail the assertion too. The
+// assertion only passes when the duration elapses, it fails when the consumer receives an error from kafka
+func (s *Suite) AssertNoKafkaMessage(topic string, duration time.Duration) bool {
+	s.T().Helper()
+	timeoutTimer := time.NewTimer(duration)
+	defer timeoutTimer.Stop()
+
+	received := make(chan *kafka.Message, 1)
+	failed := make(chan kafka.Error, 1)
+	stop := s.consume([]string{topic}, s.isolatedKafkaConfig("none"), func(message *kafka.Message) bool {
+		received <- message
+		return true
+	}, func(err kafka.Error) {
+		select {
+		case failed <- err:
+		default:
+		}
+	})
+	defer stop()
+
+	ctx := s.GetContext()
+	select {
+	case message := <-received:
+		return s.Fail(fmt.Sprintf("expected no message in topic %s within %s, got key %q value %q", topic, duration, message.Key, message.Value))
+	case err := <-failed:
+		return s.Fail(fmt.Sprintf("failed to consume topic %s while expecting no message: %v", topic, err))
+	case <-ctx.Done():
+		return s.Fail(fmt.Sprintf("context done while expecting no message in topic %s: %v", topic, context.Cause(ctx)))
+	case <-timeoutTimer.C:
+		return true
+	}
+}
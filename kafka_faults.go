@@ -0,0 +1,56 @@
+package testkit
+
+import (
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+
+	"github.com/bdpiprava/testkit/internal/kafkamock"
+)
+
+// The Kafka protocol request types of the requests commonly failed with KafkaInjectErrors
+const (
+	KafkaAPIProduce         int16 = 0
+	KafkaAPIFetch           int16 = 1
+	KafkaAPIListOffsets     int16 = 2
+	KafkaAPIMetadata        int16 = 3
+	KafkaAPIOffsetCommit    int16 = 8
+	KafkaAPIOffsetFetch     int16 = 9
+	KafkaAPIFindCoordinator int16 = 10
+	KafkaAPIJoinGroup       int16 = 11
+	KafkaAPIHeartbeat       int16 = 12
+	KafkaAPISyncGroup       int16 = 14
+	KafkaAPIInitProducerID  int16 = 22
+	KafkaAPIEndTxn          int16 = 26
+)
+
+// KafkaBrokerDown disconnects the clients from the broker of the mock cluster of the current test and refuses the
+// new connections until KafkaBrokerUp is called, the brokers are numbered from 1
+func (s *Suite) KafkaBrokerDown(brokerID int) {
+	s.Require().NoError(s.getMockCluster().SetBrokerDown(brokerID), "failed to set broker %d down", brokerID)
+}
+
+// KafkaBrokerUp accepts the connections to the broker of the mock cluster of the current test again
+func (s *Suite) KafkaBrokerUp(brokerID int) {
+	s.Require().NoError(s.getMockCluster().SetBrokerUp(brokerID), "failed to set broker %d up", brokerID)
+}
+
+// KafkaSetRtt delays the responses of the broker of the mock cluster of the current test by the round trip time
+func (s *Suite) KafkaSetRtt(brokerID int, rtt time.Duration) {
+	s.Require().NoError(s.getMockCluster().SetRoundtripDuration(brokerID, rtt), "failed to set the rtt of broker %d", brokerID)
+}
+
+// KafkaInjectErrors fails the next requests of the API key e.g. KafkaAPIProduce to the mock cluster of the current
+// test with the errors, one error per request in order. kafka.ErrTransport disconnects the client instead
+func (s *Suite) KafkaInjectErrors(apiKey int16, errs ...kafka.ErrorCode) {
+	s.Require().NoError(s.getMockCluster().PushRequestErrors(apiKey, errs...), "failed to inject errors")
+}
+
+// getMockCluster returns the mock cluster of the current test, the test fails when kafka uses the external broker
+func (s *Suite) getMockCluster() *kafkamock.Cluster {
+	cluster := s.getCluster()
+	if cluster.mock == nil {
+		s.Require().Fail("Kafka fault injection requires the mock mode of the kafka config")
+	}
+	return cluster.mock
+}
@@ -0,0 +1,285 @@
+package testkit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/pkg/errors"
+
+	"github.com/bdpiprava/testkit/kitkafka"
+)
+
+// encodingBase64 is the encoding of the records with a key, value or header which is not valid UTF-8
+const encodingBase64 = "base64"
+
+// KafkaRecord is a message recorded from a kafka topic, a line of a kafka fixture file
+type KafkaRecord struct {
+	Topic     string              `json:"topic"`
+	Partition int32               `json:"partition"`
+	Offset    int64               `json:"offset"`
+	Key       string              `json:"key,omitempty"`
+	Value     string              `json:"value"`
+	Headers   []KafkaRecordHeader `json:"headers,omitempty"`
+	Timestamp time.Time           `json:"timestamp"`
+	Encoding  string              `json:"encoding,omitempty"` // Encoding is base64 when the key, value and header values are base64 encoded
+}
+
+// KafkaRecordHeader is a header of a KafkaRecord
+type KafkaRecordHeader struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// KafkaRecording records the messages produced to topics, created by RecordTopics
+type KafkaRecording struct {
+	s        *Suite
+	topics   []string
+	stop     func()        // stop cancels the context of the recording consumer and waits for it to return
+	caughtUp chan struct{} // caughtUp is closed once the records reach the watermarks of until
+	mu       sync.Mutex
+	records  []KafkaRecord
+	until    map[string]map[int32]int64 // until are the high watermarks to record before stopping
+	stopped  bool
+}
+
+// RecordTopics starts recording the messages of the topics, from their beginning, until the recording is stopped
+//
+//	recording := s.RecordTopics("orders", "payments")
+//	... run the code under test
+//	records := recording.Save("testdata/orders.jsonl")
+func (s *Suite) RecordTopics(topics ...string) *KafkaRecording {
+	recording := &KafkaRecording{s: s, topics: topics, caughtUp: make(chan struct{})}
+	recording.stop = s.consume(topics, s.isolatedKafkaConfig("record"), func(message *kafka.Message) bool {
+		recording.record(message)
+		return false
+	}, nil)
+	return recording
+}
+
+// Stop waits for the messages produced to the topics before the call to be recorded, stops the consumer of the
+// recording and returns the records in the order they were consumed, the order of the messages of each partition
+func (r *KafkaRecording) Stop() []KafkaRecord {
+	r.s.T().Helper()
+	watermarks := r.highWatermarks()
+
+	r.mu.Lock()
+	if !r.stopped {
+		r.until = watermarks
+		r.notifyCaughtUp()
+	}
+	r.mu.Unlock()
+
+	timeoutTimer := time.NewTimer(deliveryTimeout)
+	defer timeoutTimer.Stop()
+
+	ctx := r.s.GetContext()
+	select {
+	case <-r.caughtUp:
+		r.stop()
+	case <-ctx.Done():
+		r.stop()
+		r.s.FailNow(fmt.Sprintf("context done while recording topics %v: %v", r.topics, context.Cause(ctx)))
+	case <-timeoutTimer.C:
+		r.stop()
+		r.s.FailNow(fmt.Sprintf("recording of topics %v did not catch up within %s", r.topics, deliveryTimeout))
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stopped = true
+	return append([]KafkaRecord(nil), r.records...)
+}
+
+// Save stops the recording and writes the records to the fixture file at the path, one JSON record by line
+func (r *KafkaRecording) Save(path string) []KafkaRecord {
+	r.s.T().Helper()
+	records := r.Stop()
+
+	var content bytes.Buffer
+	encoder := json.NewEncoder(&content)
+	for _, record := range records {
+		r.s.Require().NoError(encoder.Encode(record))
+	}
+	r.s.Require().NoError(os.WriteFile(path, content.Bytes(), 0600), "failed to write kafka fixture %s", path)
+	return records
+}
+
+// highWatermarks returns the offset of the next message of each partition of the topics
+func (r *KafkaRecording) highWatermarks() map[string]map[int32]int64 {
+	cluster := r.s.getCluster()
+	producer := r.s.getProducers().producer
+	watermarks := make(map[string]map[int32]int64, len(r.topics))
+	for _, topic := range r.topics {
+		watermarks[topic] = make(map[int32]int64)
+		for _, partition := range r.s.DescribeKafkaTopic(topic).Partitions {
+			_, high, err := producer.QueryWatermarkOffsets(cluster.topic(topic), partition.ID, int(deliveryTimeout.Milliseconds()))
+			r.s.Require().NoError(err, "failed to query the watermarks of %s[%d]", topic, partition.ID)
+			watermarks[topic][partition.ID] = high
+		}
+	}
+	return watermarks
+}
+
+// record appends the message to the records of the recording
+func (r *KafkaRecording) record(message *kafka.Message) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.records = append(r.records, newKafkaRecord(message))
+	r.notifyCaughtUp()
+}
+
+// notifyCaughtUp closes caughtUp once the messages before the watermarks of until are recorded, the lock of the
+// recording must be held
+func (r *KafkaRecording) notifyCaughtUp() {
+	if r.until == nil || !r.recordedUpTo(r.until) {
+		return
+	}
+	r.until = nil
+	close(r.caughtUp)
+}
+
+// recordedUpTo returns true when the messages before the high watermarks are recorded
+func (r *KafkaRecording) recordedUpTo(watermarks map[string]map[int32]int64) bool {
+	next := make(map[string]map[int32]int64)
+	for _, record := range r.records {
+		if next[record.Topic] == nil {
+			next[record.Topic] = make(map[int32]int64)
+		}
+		next[record.Topic][record.Partition] = max(next[record.Topic][record.Partition], record.Offset+1)
+	}
+
+	for topic, partitions := range watermarks {
+		for partition, high := range partitions {
+			if next[topic][partition] < high {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// ReplayKafkaFixture produces the records of the fixture file to their topic and partition, in the order of the
+// file. The {{name}} templates of the topics, keys, values and header values are replaced by the dynamic params, as
+// in the api mock files, the records encoded in base64 are not templated. The partitions of the records must exist
+func (s *Suite) ReplayKafkaFixture(path string, dynamicParams map[string]string) []kafka.TopicPartition {
+	records, err := readKafkaFixture(path)
+	s.Require().NoError(err)
+
+	messages := make([]kitkafka.Message, 0, len(records))
+	partitions := make([]int32, 0, len(records))
+	for i, record := range records {
+		message, err := record.message(dynamicParams)
+		s.Require().NoError(err, "invalid record %d of kafka fixture %s", i+1, path)
+		messages = append(messages, message)
+		partitions = append(partitions, record.Partition)
+	}
+	return s.produceBatch(messages, partitions)
+}
+
+// newKafkaRecord returns the record of the message, encoded in base64 unless its key, value and headers are UTF-8
+func newKafkaRecord(message *kafka.Message) KafkaRecord {
+	record := KafkaRecord{
+		Topic:     *message.TopicPartition.Topic,
+		Partition: message.TopicPartition.Partition,
+		Offset:    int64(message.TopicPartition.Offset),
+		Timestamp: message.Timestamp,
+	}
+
+	encode := func(data []byte) string { return string(data) }
+	if !isUTF8(message) {
+		record.Encoding = encodingBase64
+		encode = base64.StdEncoding.EncodeToString
+	}
+
+	record.Key = encode(message.Key)
+	record.Value = encode(message.Value)
+	for _, header := range message.Headers {
+		record.Headers = append(record.Headers, KafkaRecordHeader{Key: header.Key, Value: encode(header.Value)})
+	}
+	return record
+}
+
+// isUTF8 returns true if the key, value and header values of the message are valid UTF-8
+func isUTF8(message *kafka.Message) bool {
+	if !utf8.Valid(message.Key) || !utf8.Valid(message.Value) {
+		return false
+	}
+
+	for _, header := range message.Headers {
+		if !utf8.Valid(header.Value) {
+			return false
+		}
+	}
+	return true
+}
+
+// message returns the message of the record with the templates replaced by the dynamic params
+func (r KafkaRecord) message(dynamicParams map[string]string) (kitkafka.Message, error) {
+	decode := func(value string) ([]byte, error) {
+		return []byte(resolveTemplateValue(value, dynamicParams)), nil
+	}
+
+	switch r.Encoding {
+	case "":
+	case encodingBase64:
+		decode = base64.StdEncoding.DecodeString
+	default:
+		return kitkafka.Message{}, errors.Errorf("unknown encoding %q, expected %s", r.Encoding, encodingBase64)
+	}
+
+	message := kitkafka.Message{Topic: resolveTemplateValue(r.Topic, dynamicParams), Timestamp: r.Timestamp}
+	var err error
+	if r.Key != "" {
+		if message.Key, err = decode(r.Key); err != nil {
+			return message, errors.Wrap(err, "invalid key")
+		}
+	}
+
+	if message.Value, err = decode(r.Value); err != nil {
+		return message, errors.Wrap(err, "invalid value")
+	}
+
+	for _, header := range r.Headers {
+		value, err := decode(header.Value)
+		if err != nil {
+			return message, errors.Wrapf(err, "invalid header %s", header.Key)
+		}
+		message.Headers = append(message.Headers, kafka.Header{Key: header.Key, Value: value})
+	}
+	return message, nil
+}
+
+// readKafkaFixture reads the records of the fixture file, one JSON record by line, the blank lines are ignored
+func readKafkaFixture(path string) ([]KafkaRecord, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open kafka fixture %s", path)
+	}
+	defer func() { _ = file.Close() }()
+
+	records := make([]KafkaRecord, 0)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		content := bytes.TrimSpace(scanner.Bytes())
+		if len(content) == 0 {
+			continue
+		}
+
+		var record KafkaRecord
+		if err = json.Unmarshal(content, &record); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse line %d of kafka fixture %s", line, path)
+		}
+		records = append(records, record)
+	}
+	return records, errors.Wrapf(scanner.Err(), "failed to read kafka fixture %s", path)
+}
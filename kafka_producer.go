@@ -0,0 +1,236 @@
+package testkit
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/bdpiprava/testkit/kitkafka"
+)
+
+// testProducers are the producers of a test, created on first use and closed when the test completes
+type testProducers struct {
+	cluster       *kafkaCluster
+	producer      *kafka.Producer
+	transactional *kafka.Producer // transactional is created by the first BeginTx of the test
+	inTransaction bool            // inTransaction is true from BeginTx to CommitTx or AbortTx
+}
+
+// Close closes the producers of the test
+func (p *testProducers) Close() {
+	if p.producer != nil {
+		p.producer.Close()
+	}
+
+	if p.transactional != nil {
+		p.transactional.Close()
+	}
+}
+
+// ProduceBatch produces the messages to the kafka cluster and waits for all of their delivery reports. It returns the
+// partitions and offsets the messages were delivered to, in the order of the messages. The test fails with the
+// delivery errors grouped by topic and error when any message is not delivered. The messages are part of the
+// transaction of the test between BeginTx and CommitTx or AbortTx
+func (s *Suite) ProduceBatch(messages []kitkafka.Message) []kafka.TopicPartition {
+	return s.produceBatch(messages, nil)
+}
+
+// produceBatch produces the messages to the partitions, the partition of each message is chosen by the partitioner
+// without partitions
+func (s *Suite) produceBatch(messages []kitkafka.Message, partitions []int32) []kafka.TopicPartition {
+	producers := s.getProducers()
+	producer := producers.producer
+	if producers.inTransaction {
+		producer = producers.transactional
+	}
+
+	log := s.Logger().WithFields(logrus.Fields{
+		"test":     s.T().Name(),
+		"func":     "Produce",
+		"messages": len(messages),
+		"server":   producers.cluster.BootstrapServers(),
+	})
+
+	log.Info("Producing messages")
+	deliveryChan := make(chan kafka.Event, len(messages))
+	for i, message := range messages {
+		topic := producers.cluster.topic(message.Topic)
+		partition := kafka.PartitionAny
+		if partitions != nil {
+			partition = partitions[i]
+		}
+
+		produced := &kafka.Message{
+			TopicPartition: kafka.TopicPartition{
+				Topic:     &topic,
+				Partition: partition,
+			},
+			Headers:       message.Headers,
+			Key:           message.Key,
+			Value:         message.Value,
+			Timestamp:     message.Timestamp,
+			TimestampType: message.TimestampType,
+			Opaque:        i,
+		}
+
+		err := producer.Produce(produced, deliveryChan)
+		for isQueueFull(err) {
+			producer.Flush(int(pollTimeout.Milliseconds()))
+			err = producer.Produce(produced, deliveryChan)
+		}
+		s.Require().NoError(err)
+	}
+
+	log.Info("Waiting for delivery confirmation")
+	delivered := make([]kafka.TopicPartition, len(messages))
+	failures := make(map[string]int)
+	timeoutTimer := time.NewTimer(deliveryTimeout)
+	defer timeoutTimer.Stop()
+
+	ctx := s.GetContext()
+	for received := 0; received < len(messages); {
+		select {
+		case <-timeoutTimer.C:
+			s.FailNow("Delivery timeout")
+		case <-ctx.Done():
+			s.FailNow(fmt.Sprintf("Delivery cancelled: %v", context.Cause(ctx)))
+		case event := <-deliveryChan:
+			report, ok := event.(*kafka.Message)
+			if !ok {
+				continue
+			}
+
+			received++
+			partition := report.TopicPartition
+			topic := strings.TrimPrefix(*partition.Topic, producers.cluster.prefix)
+			partition.Topic = &topic
+			delivered[report.Opaque.(int)] = partition
+			if partition.Error != nil {
+				failures[fmt.Sprintf("%s: %v", topic, partition.Error)]++
+			}
+		}
+	}
+
+	if len(failures) > 0 {
+		s.FailNow(deliveryFailure(failures, len(messages)))
+	}
+	log.Info("Delivered")
+	return delivered
+}
+
+// BeginTx begins a transaction, the messages produced by the test are committed by CommitTx or aborted by AbortTx.
+// The transactional producer of the test is created by the first call
+func (s *Suite) BeginTx() {
+	producers := s.getProducers()
+	s.Require().False(producers.inTransaction, "a transaction is already in progress")
+
+	if producers.transactional == nil {
+		config := s.getKafkaConfig()
+		s.Require().NoError(config.SetKey("transactional.id", fmt.Sprintf("%s%s.%d", producers.cluster.prefix, s.T().Name(), time.Now().UnixNano())))
+
+		producer, err := kafka.NewProducer(config)
+		s.Require().NoError(err)
+		producers.transactional = producer
+
+		ctx, cancel := context.WithTimeout(s.GetContext(), deliveryTimeout)
+		defer cancel()
+		s.Require().NoError(producer.InitTransactions(ctx), "failed to initialise the transactions")
+	}
+
+	s.Require().NoError(producers.transactional.BeginTransaction())
+	producers.inTransaction = true
+}
+
+// CommitTx commits the transaction begun by BeginTx, the messages produced in the transaction become visible to the
+// read_committed consumers
+func (s *Suite) CommitTx() {
+	producers := s.transactionProducers()
+	ctx, cancel := context.WithTimeout(s.GetContext(), deliveryTimeout)
+	defer cancel()
+
+	s.Require().NoError(producers.transactional.CommitTransaction(ctx), "failed to commit the transaction")
+	producers.inTransaction = false
+}
+
+// AbortTx aborts the transaction begun by BeginTx, the messages produced in the transaction are never visible to the
+// read_committed consumers of the external broker, the mock cluster does not filter them
+func (s *Suite) AbortTx() {
+	producers := s.transactionProducers()
+	ctx, cancel := context.WithTimeout(s.GetContext(), deliveryTimeout)
+	defer cancel()
+
+	s.Require().NoError(producers.transactional.AbortTransaction(ctx), "failed to abort the transaction")
+	producers.inTransaction = false
+}
+
+// transactionProducers returns the producers of the test, failing the test when no transaction is in progress
+func (s *Suite) transactionProducers() *testProducers {
+	producers := s.getProducers()
+	s.Require().True(producers.inTransaction, "no transaction in progress, call BeginTx first")
+	return producers
+}
+
+// getProducers returns the producers of the test, creating the producer of the test on the first call. The producers
+// are closed when the test completes, before the kafka cluster of the test is released
+func (s *Suite) getProducers() *testProducers {
+	t := s.T()
+	cluster := s.getCluster()
+	config := s.getKafkaConfig()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if producers, ok := s.kafkaProducers[t.Name()]; ok && producers.cluster == cluster {
+		return producers
+	}
+
+	producer, err := kafka.NewProducer(config)
+	s.Require().NoError(err)
+
+	if previous, ok := s.kafkaProducers[t.Name()]; ok {
+		previous.Close()
+	} else {
+		t.Cleanup(func() {
+			s.closeProducers(t.Name())
+		})
+	}
+
+	producers := &testProducers{cluster: cluster, producer: producer}
+	s.kafkaProducers[t.Name()] = producers
+	return producers
+}
+
+// closeProducers closes the producers of the test unless they are already closed
+func (s *Suite) closeProducers(testName string) {
+	mu.Lock()
+	producers, ok := s.kafkaProducers[testName]
+	delete(s.kafkaProducers, testName)
+	mu.Unlock()
+
+	if ok {
+		producers.Close()
+	}
+}
+
+// isQueueFull returns true if the error is the local queue of the producer being full
+func isQueueFull(err error) bool {
+	var kafkaErr kafka.Error
+	return errors.As(err, &kafkaErr) && kafkaErr.Code() == kafka.ErrQueueFull
+}
+
+// deliveryFailure returns the message of the delivery errors of a batch, the count of each topic and error
+func deliveryFailure(failures map[string]int, total int) string {
+	failed := 0
+	lines := make([]string, 0, len(failures))
+	for _, failure := range slices.Sorted(maps.Keys(failures)) {
+		failed += failures[failure]
+		lines = append(lines, fmt.Sprintf("  - %s (%d messages)", failure, failures[failure]))
+	}
+	return fmt.Sprintf("Delivery failed for %d of %d messages:\n%s", failed, total, strings.Join(lines, "\n"))
+}
@@ -1,21 +1,326 @@
 package testkit
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"maps"
+	"net/http/httptest"
+	"regexp"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 
+	"github.com/bdpiprava/testkit/internal"
+	"github.com/bdpiprava/testkit/internal/kafkamock"
+	"github.com/bdpiprava/testkit/internal/schemaregistry"
 	"github.com/bdpiprava/testkit/kitkafka"
 )
 
-const pollTimeout = 10 * time.Second
+const pollTimeout = 100 * time.Millisecond
 const deliveryTimeout = 10 * time.Second
 
-var mu sync.RWMutex
+const kafkaProviderName = "kafka"
+
+// KafkaOptions are the options of the kafka cluster of a test
+type KafkaOptions struct {
+	Brokers int         // Brokers is the number of brokers of the mock cluster, one by default
+	Topics  []TopicSpec // Topics are the topics created for the test
+}
+
+// TopicSpec is the specification of a topic created for a test
+type TopicSpec struct {
+	Name              string            // Name of the topic
+	Partitions        int               // Partitions of the topic, the partitions of the kafka config by default
+	ReplicationFactor int               // ReplicationFactor of the topic, the replication factor of the kafka config by default
+	Config            map[string]string // Config of the topic e.g. cleanup.policy, only applied on the external broker
+}
+
+// withDefaults returns the spec with the partitions and replication factor of the config when they are not set
+func (t TopicSpec) withDefaults(config internal.KafkaConfig) TopicSpec {
+	if t.Partitions == 0 {
+		t.Partitions = config.Partitions
+	}
+
+	if t.ReplicationFactor == 0 {
+		t.ReplicationFactor = config.ReplicationFactor
+	}
+	return t
+}
+
+// topicSpecs returns the specs of the topics with the default partitions and replication factor
+func topicSpecs(names []string) []TopicSpec {
+	specs := make([]TopicSpec, 0, len(names))
+	for _, name := range names {
+		specs = append(specs, TopicSpec{Name: name})
+	}
+	return specs
+}
+
+// topicNames returns the names of the topics
+func topicNames(topics []TopicSpec) []string {
+	names := make([]string, 0, len(topics))
+	for _, topic := range topics {
+		names = append(names, topic.Name)
+	}
+	return names
+}
+
+// maxTopicPrefixLength keeps the prefixed topic names under the limit of 249 characters
+const maxTopicPrefixLength = 150
+
+// invalidTopicChars matches the characters which are not allowed in topic names
+var invalidTopicChars = regexp.MustCompile(`[^a-zA-Z0-9._-]`)
+
+func init() {
+	RegisterProvider(&kafkaProvider{})
+}
+
+// kafkaProvider provides a mock kafka cluster to each test requiring kafka, or the topics of the test on the
+// external broker when the kafka section selects the broker mode
+type kafkaProvider struct {
+	config internal.KafkaConfig
+	admin  *kafka.AdminClient
+}
+
+// kafkaCluster is the kafka cluster acquired by a test, the mock cluster of the test or the external broker
+type kafkaCluster struct {
+	mock             *kafkamock.Cluster // mock is nil for the external broker
+	bootstrapServers string
+	prefix           string             // prefix of the topics of the test on the external broker
+	client           map[string]string  // client are the properties of the clients connecting to the cluster
+	admin            *kafka.AdminClient // admin is the client of the external broker, created on first use for the mock cluster
+	adminOnce        sync.Once
+	adminErr         error
+	keepTopics       bool             // keepTopics keeps the topics of the test on the external broker after release
+	registry         *httptest.Server // registry is the schema registry of the test, started on first use
+	registryOnce     sync.Once
+}
+
+// BootstrapServers returns the bootstrap servers of the cluster
+func (c *kafkaCluster) BootstrapServers() string {
+	return c.bootstrapServers
+}
+
+// topic returns the name of the topic on the cluster
+func (c *kafkaCluster) topic(name string) string {
+	return c.prefix + name
+}
+
+// adminClient returns the admin client of the cluster, created on first use for the mock cluster
+func (c *kafkaCluster) adminClient() (*kafka.AdminClient, error) {
+	c.adminOnce.Do(func() {
+		if c.admin == nil {
+			c.admin, c.adminErr = kafka.NewAdminClient(configMap(c.client))
+			c.adminErr = errors.Wrap(c.adminErr, "failed to create kafka admin client")
+		}
+	})
+	return c.admin, c.adminErr
+}
+
+// schemaRegistry returns the schema registry of the cluster, started on first use
+func (c *kafkaCluster) schemaRegistry() *httptest.Server {
+	c.registryOnce.Do(func() {
+		c.registry = httptest.NewServer(schemaregistry.New())
+	})
+	return c.registry
+}
+
+// Release stops the schema registry and closes the mock cluster, or deletes the topics with the prefix of the test on
+// the external broker, including the ones auto created by the producers
+func (c *kafkaCluster) Release() error {
+	c.registryOnce.Do(func() {})
+	if c.registry != nil {
+		c.registry.Close()
+	}
+
+	if c.mock != nil {
+		if c.admin != nil {
+			c.admin.Close()
+		}
+		c.mock.Close()
+		return nil
+	}
+
+	if c.keepTopics {
+		return nil
+	}
+
+	metadata, err := c.admin.GetMetadata(nil, true, int(deliveryTimeout.Milliseconds()))
+	if err != nil {
+		return errors.Wrap(err, "failed to list kafka topics")
+	}
+
+	var topics []string
+	for topic := range metadata.Topics {
+		if strings.HasPrefix(topic, c.prefix) {
+			topics = append(topics, topic)
+		}
+	}
+
+	if len(topics) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), deliveryTimeout)
+	defer cancel()
+	results, err := c.admin.DeleteTopics(ctx, topics)
+	if err != nil {
+		return errors.Wrap(err, "failed to delete kafka topics")
+	}
+	return topicResultsError(results)
+}
+
+// Name returns the name of the provider
+func (p *kafkaProvider) Name() string {
+	return kafkaProviderName
+}
+
+// Init initializes the provider from the kafka section of the config file, the mock mode is used without it
+func (p *kafkaProvider) Init(config ConfigSection, _ logrus.FieldLogger) (err error) {
+	if err = config.Decode(&p.config); err != nil {
+		return err
+	}
+
+	if err = internal.ApplyDefaults(&p.config); err != nil {
+		return err
+	}
+
+	if p.config.Mode != internal.KafkaModeBroker {
+		return nil
+	}
+
+	p.admin, err = kafka.NewAdminClient(configMap(p.config.ClientConfig()))
+	return errors.Wrap(err, "failed to create kafka admin client")
+}
+
+// Acquire creates a mock cluster with the brokers and the topics of the KafkaOptions given as options, or creates the
+// topics prefixed by the name of the test on the external broker
+func (p *kafkaProvider) Acquire(ctx context.Context, request AcquireRequest) (Resource, error) {
+	options, _ := request.Options.(KafkaOptions)
+	topics := make([]TopicSpec, 0, len(options.Topics))
+	for _, topic := range options.Topics {
+		topics = append(topics, topic.withDefaults(p.config))
+	}
+
+	if p.config.Mode == internal.KafkaModeBroker {
+		return p.acquireTopics(ctx, request, topics)
+	}
+
+	request.Logger.Debugf("Creating new Kafka cluster with %d brokers", max(options.Brokers, 1))
+	cluster, err := kafkamock.NewCluster(max(options.Brokers, 1))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create kafka mock cluster")
+	}
+
+	request.Logger.Infof("Creating topics: %v", topicNames(topics))
+	for _, topic := range topics {
+		if err = cluster.CreateTopic(topic.Name, topic.Partitions, topic.ReplicationFactor); err != nil {
+			cluster.Close()
+			return nil, errors.Wrapf(err, "failed to create topic %s", topic.Name)
+		}
+	}
+
+	request.Logger.Infof("Topics created: %v", topicNames(topics))
+	return &kafkaCluster{
+		mock:             cluster,
+		bootstrapServers: cluster.BootstrapServers(),
+		client:           map[string]string{"bootstrap.servers": cluster.BootstrapServers()},
+	}, nil
+}
+
+// acquireTopics creates the topics of the test on the external broker
+func (p *kafkaProvider) acquireTopics(ctx context.Context, request AcquireRequest, topics []TopicSpec) (Resource, error) {
+	cluster := &kafkaCluster{
+		bootstrapServers: p.config.BootstrapServers,
+		prefix:           topicPrefix(request.TestName),
+		client:           p.config.ClientConfig(),
+		admin:            p.admin,
+		keepTopics:       p.config.KeepTopics,
+	}
+
+	if len(topics) == 0 {
+		return cluster, nil
+	}
+
+	specifications := make([]kafka.TopicSpecification, 0, len(topics))
+	for _, topic := range topics {
+		specifications = append(specifications, kafka.TopicSpecification{
+			Topic:             cluster.topic(topic.Name),
+			NumPartitions:     topic.Partitions,
+			ReplicationFactor: topic.ReplicationFactor,
+			Config:            topic.Config,
+		})
+	}
+
+	request.Logger.Infof("Creating topics with prefix %s: %v", cluster.prefix, topicNames(topics))
+	results, err := p.admin.CreateTopics(ctx, specifications)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create kafka topics")
+	}
+
+	if err = topicResultsError(results); err != nil {
+		if releaseErr := cluster.Release(); releaseErr != nil {
+			request.Logger.WithError(releaseErr).Warn("failed to delete the topics created")
+		}
+		return nil, err
+	}
+	return cluster, nil
+}
+
+// HealthCheck requests the metadata of the external broker, the mock clusters are created in process
+func (p *kafkaProvider) HealthCheck(ctx context.Context) error {
+	if p.admin == nil {
+		return nil
+	}
+
+	timeout := deliveryTimeout
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+
+	_, err := p.admin.GetMetadata(nil, false, int(timeout.Milliseconds()))
+	return err
+}
+
+// topicPrefix returns the prefix of the topics of the test, unique to isolate the test on a shared broker
+func topicPrefix(testName string) string {
+	name := invalidTopicChars.ReplaceAllString(testName, "_")
+	if len(name) > maxTopicPrefixLength {
+		name = name[:maxTopicPrefixLength]
+	}
+	return fmt.Sprintf("%s.%s.", name, uuid.NewString())
+}
+
+// topicResultsError returns the errors of the topic results, nil if all succeeded
+func topicResultsError(results []kafka.TopicResult) error {
+	var failures []string
+	for _, result := range results {
+		if result.Error.Code() != kafka.ErrNoError {
+			failures = append(failures, fmt.Sprintf("%s: %v", result.Topic, result.Error))
+		}
+	}
+
+	if len(failures) > 0 {
+		return errors.Errorf("kafka topics failed: %s", strings.Join(failures, ", "))
+	}
+	return nil
+}
+
+// configMap returns the properties as a kafka config map
+func configMap(properties map[string]string) *kafka.ConfigMap {
+	config := make(kafka.ConfigMap, len(properties))
+	for key, value := range properties {
+		config[key] = value
+	}
+	return &config
+}
 
 // OnMessage is a callback function that is called when a message is received
 type OnMessage func(*kafka.Message) bool
@@ -23,35 +328,51 @@ type OnMessage func(*kafka.Message) bool
 // RequiresKafka is a helper function to get the test database based on configuration
 // returns the server address
 func (s *Suite) RequiresKafka(topics ...string) string {
+	return s.requiresKafka(ScopeSubtest, "RequiresKafka", KafkaOptions{Topics: topicSpecs(topics)})
+}
+
+// RequiresKafkaIn returns the server address of the kafka cluster of the scope, the cluster is created with the topics
+// when the scope does not have one and closed when the test owning the scope completes
+func (s *Suite) RequiresKafkaIn(scope Scope, topics ...string) string {
+	return s.requiresKafka(scope, "RequiresKafkaIn", KafkaOptions{Topics: topicSpecs(topics)})
+}
+
+// RequiresKafkaWith returns the server address of the kafka cluster of the current test, the cluster is created with
+// the brokers and the topics of the options when the test does not have one
+func (s *Suite) RequiresKafkaWith(options KafkaOptions) string {
+	return s.requiresKafka(ScopeSubtest, "RequiresKafkaWith", options)
+}
+
+// requiresKafka returns the server address of the kafka cluster of the scope, creating it with the options when the
+// scope does not have one
+func (s *Suite) requiresKafka(scope Scope, caller string, options KafkaOptions) string {
+	owner := s.scopeOwner(scope)
 	log := s.Logger().WithFields(logrus.Fields{
-		"test": s.T().Name(),
-		"func": "RequiresKafka",
+		"test":  owner.Name(),
+		"func":  caller,
+		"scope": scope,
 	})
 
-	mu.RLock()
-	if cluster, ok := s.kafkaServers[s.T().Name()]; ok {
+	if resource, ok := s.lookupResourceOf(owner.Name(), kafkaProviderName, false); ok {
+		cluster := resource.(*kafkaCluster)
 		log.Tracef("Kafka cluster already exists, returning bootstrap servers %s", cluster.BootstrapServers())
-		mu.RUnlock()
 		return cluster.BootstrapServers()
 	}
-	mu.RUnlock()
 
-	log.Trace("Creating new Kafka cluster")
-	cluster, err := kafka.NewMockCluster(1)
-	s.Require().NoError(err)
-
-	log.Infof("Creating topics: %v", topics)
-	for _, topic := range topics {
-		s.Require().NoError(cluster.CreateTopic(topic, 1, 1))
+	cluster := s.AcquireResourceIn(scope, kafkaProviderName, "", options).(*kafkaCluster)
+	s.suiteStats().recordResource(owner, "kafka.bootstrap_servers", cluster.BootstrapServers())
+	if cluster.prefix != "" {
+		s.suiteStats().recordResource(owner, "kafka.topic_prefix", cluster.prefix)
 	}
-
-	log.Infof("Topics created: %v", topics)
-	mu.Lock()
-	defer mu.Unlock()
-	s.kafkaServers[s.T().Name()] = cluster
 	return cluster.BootstrapServers()
 }
 
+// KafkaTopic returns the name of the topic on the kafka cluster of the test, the name prefixed for the test on the
+// external broker, to be passed to the code under test
+func (s *Suite) KafkaTopic(name string) string {
+	return s.getCluster().topic(name)
+}
+
 // Produce a message to the kafka topic
 func (s *Suite) Produce(topic string, key, value []byte, headers ...kafka.Header) {
 	s.ProduceMessage(kitkafka.Message{
@@ -62,49 +383,25 @@ func (s *Suite) Produce(topic string, key, value []byte, headers ...kafka.Header
 	})
 }
 
-// ProduceMessage produce a message to kafka cluster
+// ProduceMessage produce a message to kafka cluster with the producer of the test, it waits for the delivery report
 func (s *Suite) ProduceMessage(message kitkafka.Message) {
-	servers := s.getCluster().BootstrapServers()
-	log := s.Logger().WithFields(logrus.Fields{
-		"test":   s.T().Name(),
-		"func":   "Produce",
-		"topic":  message.Topic,
-		"server": servers,
-	})
-
-	log.Info("Creating producer")
-	producer, err := kafka.NewProducer(s.getKafkaConfig())
-	s.Require().NoError(err)
-	defer producer.Close()
-
-	log.Info("Producing message")
-	deliveryChan := make(chan kafka.Event)
-	err = producer.Produce(&kafka.Message{
-		TopicPartition: kafka.TopicPartition{
-			Topic:     &message.Topic,
-			Partition: kafka.PartitionAny,
-		},
-		Headers:       message.Headers,
-		Key:           message.Key,
-		Value:         message.Value,
-		Timestamp:     message.Timestamp,
-		TimestampType: message.TimestampType,
-	}, deliveryChan)
-	s.Require().NoError(err)
-
-	log.Info("Waiting for delivery confirmation")
-	select {
-	case <-time.After(deliveryTimeout):
-		s.FailNow("Delivery timeout")
-	case <-deliveryChan:
-		log.Info("Delivered")
-		break
-	}
+	s.ProduceBatch([]kitkafka.Message{message})
 }
 
-// Consume a message from the kafka topic
+// Consume a message from the kafka topic, consuming stops when the callback returns true or the test completes.
+// The consumer is closed when the test completes, before the kafka cluster of the test is released. The topics of
+// the messages passed to the callback are the names given to Consume, without the prefix of the test
 func (s *Suite) Consume(topics []string, callback OnMessage) {
-	servers := s.getCluster().BootstrapServers()
+	s.consume(topics, s.getKafkaConfig(), callback, nil)
+}
+
+// consume consumes the topics with the consumer config until the callback returns true or the test completes, the
+// errors received from kafka are passed to onError when given, otherwise they are only logged. The returned function
+// stops consuming and waits for the consumer to return
+func (s *Suite) consume(topics []string, config *kafka.ConfigMap, callback OnMessage, onError func(kafka.Error)) func() {
+	t := s.T()
+	cluster := s.getCluster()
+	servers := cluster.BootstrapServers()
 	log := s.Logger().WithFields(logrus.Fields{
 		"test":   s.T().Name(),
 		"func":   "Consume",
@@ -114,31 +411,63 @@ func (s *Suite) Consume(topics []string, callback OnMessage) {
 
 	s.NotNil(callback, "callback is required")
 	log.Info("Creating consumer")
-	consumer, err := kafka.NewConsumer(s.getKafkaConfig())
+	consumer, err := kafka.NewConsumer(config)
 	s.Require().NoError(err)
 	mu.Lock()
-	defer mu.Unlock()
-	s.kafkaConsumers = append(s.kafkaConsumers, consumer)
-	s.Require().NoError(consumer.SubscribeTopics(topics, nil))
+	s.kafkaConsumers[t.Name()] = append(s.kafkaConsumers[t.Name()], consumer)
+	mu.Unlock()
+
+	ctx, stop := context.WithCancel(s.GetContext())
+	done := make(chan struct{})
+	t.Cleanup(func() {
+		stop()
+		<-done
+		s.closeConsumer(t.Name(), consumer)
+	})
+
+	subscribed := make([]string, 0, len(topics))
+	for _, topic := range topics {
+		subscribed = append(subscribed, cluster.topic(topic))
+	}
+
+	if cluster.prefix != "" && callback != nil {
+		callback = withoutTopicPrefix(cluster.prefix, callback)
+	}
+
+	if err = consumer.SubscribeTopics(subscribed, nil); err != nil {
+		close(done)
+		s.Require().NoError(err)
+	}
 
 	go func(consumer *kafka.Consumer) {
+		defer close(done)
 		var wg sync.WaitGroup
 		for {
 			wg.Add(1)
-			if s.doConsume(consumer, log, callback, &wg) {
+			if s.doConsume(ctx, consumer, log, callback, onError, &wg) {
 				break
 			}
 			wg.Wait()
 		}
 	}(consumer)
+
+	return func() {
+		stop()
+		<-done
+	}
 }
 
-func (s *Suite) doConsume(consumer *kafka.Consumer, log *logrus.Entry, callback OnMessage, wg *sync.WaitGroup) bool {
+func (s *Suite) doConsume(ctx context.Context, consumer *kafka.Consumer, log *logrus.Entry, callback OnMessage, onError func(kafka.Error), wg *sync.WaitGroup) bool {
 	defer wg.Done()
 	if consumer.IsClosed() {
 		return true
 	}
 
+	if ctx.Err() != nil {
+		log.Infof("Stop consuming: %v", context.Cause(ctx))
+		return true
+	}
+
 	ev := consumer.Poll(int(pollTimeout.Milliseconds()))
 	switch e := ev.(type) {
 	case *kafka.Message:
@@ -148,6 +477,9 @@ func (s *Suite) doConsume(consumer *kafka.Consumer, log *logrus.Entry, callback
 		log.Info("Partition EOF")
 	case kafka.Error:
 		log.Warn(fmt.Sprintf("Received error from kafka: %#v", e))
+		if onError != nil {
+			onError(e)
+		}
 	case kafka.AssignedPartitions:
 		s.Require().NoError(consumer.Assign(e.Partitions))
 	}
@@ -157,67 +489,135 @@ func (s *Suite) doConsume(consumer *kafka.Consumer, log *logrus.Entry, callback
 
 // WaitForMessage waits for a message to be consumed from the kafka topics
 func (s *Suite) WaitForMessage(topic string, timout time.Duration) (*kafka.Message, error) {
+	return s.waitForMessage(topic, timout, s.getKafkaConfig(), func(*kafka.Message) bool { return true })
+}
+
+// AssertKafkaPartition asserts the message with the key produced to the topic is in the partition, it waits for
+// the message up to the timeout. The topic is read from the beginning by a consumer group of its own
+func (s *Suite) AssertKafkaPartition(topic string, key []byte, partition int32, timeout time.Duration) bool {
+	message, err := s.waitForMessage(topic, timeout, s.isolatedKafkaConfig("partition"), func(message *kafka.Message) bool {
+		return bytes.Equal(message.Key, key)
+	})
+	if !s.NoError(err, "message with key %q not found", key) {
+		return false
+	}
+	return s.Equal(partition, message.TopicPartition.Partition, "partition of the message with key %q in topic %s", key, topic)
+}
+
+// waitForMessage waits for the first message consumed from the topic matching the filter
+func (s *Suite) waitForMessage(topic string, timout time.Duration, config *kafka.ConfigMap, matches func(*kafka.Message) bool) (*kafka.Message, error) {
 	timeoutTimer := time.NewTimer(timout)
 	defer timeoutTimer.Stop()
 
 	done := make(chan struct{})
 	received := make(chan *kafka.Message, 1)
-	s.Consume([]string{topic}, func(msg *kafka.Message) bool {
+	s.consume([]string{topic}, config, func(msg *kafka.Message) bool {
+		if !matches(msg) {
+			return false
+		}
 		received <- msg
 		close(done)
 		return true
-	})
+	}, nil)
 
 	// Then - wait for the message to be consumed
+	ctx := s.GetContext()
 	select {
 	case <-done:
 		return <-received, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("context done while waiting for the message in topic %s: %w", topic, context.Cause(ctx))
 	case <-timeoutTimer.C:
 		return nil, fmt.Errorf("timeout reached while waiting for the message in topic %s", topic)
 	}
 }
 
+// getKafkaConfig returns the config of the clients of the test, the client overrides of the config file take
+// precedence
 func (s *Suite) getKafkaConfig() *kafka.ConfigMap {
-	return &kafka.ConfigMap{
-		"bootstrap.servers": s.getCluster().BootstrapServers(),
-		"group.id":          s.T().Name(),
+	cluster := s.getCluster()
+	properties := map[string]string{
+		"group.id":          cluster.prefix + s.T().Name(),
 		"auto.offset.reset": "earliest",
 	}
+	maps.Copy(properties, cluster.client)
+	return configMap(properties)
 }
 
-// getCluster returns the kafka cluster for current test or from parent tests or suite
-func (s *Suite) getCluster() *kafka.MockCluster {
-	mu.RLock()
-	defer mu.RUnlock()
-	name := s.T().Name()
-	for {
-		cluster, ok := s.kafkaServers[name]
-		if ok {
-			return cluster
-		}
+// isolatedKafkaConfig returns the config of a consumer reading the topics from the beginning in a group of its own,
+// unaffected by the offsets committed by the other consumers of the test
+func (s *Suite) isolatedKafkaConfig(purpose string) *kafka.ConfigMap {
+	config := s.getKafkaConfig()
+	group, _ := config.Get("group.id", "")
+	s.Require().NoError(config.SetKey("group.id", fmt.Sprintf("%v.%s.%s", group, purpose, uuid.NewString())))
+	return config
+}
 
-		if idx := strings.LastIndex(name, "/"); idx <= 0 {
-			break
+// SchemaRegistryURL returns the URL of the in process schema registry of the kafka cluster of the test, started on the
+// first call
+func (s *Suite) SchemaRegistryURL() string {
+	return s.getCluster().schemaRegistry().URL
+}
+
+// SchemaRegistry returns a client of the in process schema registry of the kafka cluster of the test
+func (s *Suite) SchemaRegistry() *kitkafka.SchemaRegistryClient {
+	return kitkafka.NewSchemaRegistryClient(s.SchemaRegistryURL())
+}
+
+// withoutTopicPrefix returns the callback receiving the messages with the prefix of the test removed from the topic
+func withoutTopicPrefix(prefix string, callback OnMessage) OnMessage {
+	return func(message *kafka.Message) bool {
+		if message.TopicPartition.Topic != nil {
+			topic := strings.TrimPrefix(*message.TopicPartition.Topic, prefix)
+			message.TopicPartition.Topic = &topic
 		}
+		return callback(message)
+	}
+}
 
-		name = name[:strings.LastIndex(name, "/")]
+// getCluster returns the kafka cluster for current test or from parent tests or suite
+func (s *Suite) getCluster() *kafkaCluster {
+	if resource, ok := s.Resource(kafkaProviderName); ok {
+		return resource.(*kafkaCluster)
 	}
 
 	s.Require().Fail("Kafka cluster not found. call RequiresKafka before calling Produce")
 	return nil
 }
 
-// cleanKafkaResources closes the kafka consumers and servers
-func (s *Suite) cleanKafkaResources() {
-	mu.RLock()
-	defer mu.RUnlock()
-	for _, c := range s.kafkaConsumers {
-		closeSilently(c)
+// closeConsumer closes the consumer created by the test unless it is already closed
+func (s *Suite) closeConsumer(testName string, consumer *kafka.Consumer) {
+	mu.Lock()
+	idx := slices.Index(s.kafkaConsumers[testName], consumer)
+	if idx < 0 {
+		mu.Unlock()
+		return
 	}
+	s.kafkaConsumers[testName] = slices.Delete(s.kafkaConsumers[testName], idx, idx+1)
+	mu.Unlock()
+
+	closeSilently(consumer)
+}
+
+// cleanKafkaResources closes the kafka consumers and producers owned by the test
+func (s *Suite) cleanKafkaResources(testName string) {
+	mu.Lock()
+	defer mu.Unlock()
+	for key, producers := range s.kafkaProducers {
+		if ownedBy(key, testName) {
+			producers.Close()
+			delete(s.kafkaProducers, key)
+		}
+	}
+
+	for key, consumers := range s.kafkaConsumers {
+		if !ownedBy(key, testName) {
+			continue
+		}
 
-	for _, server := range s.kafkaServers {
-		if server != nil {
-			server.Close()
+		for _, c := range consumers {
+			closeSilently(c)
 		}
+		delete(s.kafkaConsumers, key)
 	}
 }
@@ -1,14 +1,24 @@
 package testkit_test
 
 import (
+	"fmt"
+	"hash/crc32"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
 	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/bdpiprava/testkit"
+	"github.com/bdpiprava/testkit/kitkafka"
 )
 
 type KafkaTestSuiteTest struct {
@@ -38,10 +48,10 @@ func (s *KafkaTestSuiteTest) Test_ShouldGetKafkaServerFromTheParentTestWhenNotDe
 					wg.Done()
 					return true
 				})
+				wg.Wait()
 			})
 		})
 	})
-	wg.Wait()
 }
 
 func (s *KafkaTestSuiteTest) Test_ShouldGetKafkaServerFromTheSuiteWhenNotDeclaredAtTestLevel() {
@@ -55,10 +65,10 @@ func (s *KafkaTestSuiteTest) Test_ShouldGetKafkaServerFromTheSuiteWhenNotDeclare
 					wg.Done()
 					return true
 				})
+				wg.Wait()
 			})
 		})
 	})
-	wg.Wait()
 }
 
 func (s *KafkaTestSuiteTest) Test_RequiresKafka() {
@@ -118,3 +128,375 @@ func (s *KafkaTestSuiteTest) Test_WaitForMessage_Timeout() {
 	s.Nil(got)
 	s.EqualError(gotErr, "timeout reached while waiting for the message in topic "+topic)
 }
+
+type KafkaBrokerSuiteTest struct {
+	testkit.Suite
+}
+
+func TestKafkaBrokerSuiteTest(t *testing.T) {
+	// the mock cluster stands for the external broker, it creates the topics on the first use and does not support
+	// deleting them
+	broker, err := kafka.NewMockCluster(1)
+	require.NoError(t, err)
+	t.Cleanup(broker.Close)
+
+	testkit.RunWithConfig(t, new(KafkaBrokerSuiteTest), testkit.SuiteConfig{
+		Kafka: &testkit.KafkaConfig{
+			Mode:             "broker",
+			BootstrapServers: broker.BootstrapServers(),
+			KeepTopics:       true,
+		},
+	})
+}
+
+func (s *KafkaBrokerSuiteTest) Test_PrefixesTheTopicsOfTheTest() {
+	s.RequiresKafka()
+	topic := s.KafkaTopic("orders")
+	s.Require().True(strings.HasPrefix(topic, "TestKafkaBrokerSuiteTest_Test_PrefixesTheTopicsOfTheTest."), topic)
+
+	s.Produce("orders", []byte("key"), []byte("value"))
+	message, err := s.WaitForMessage("orders", 10*time.Second)
+
+	s.Require().NoError(err)
+	s.Equal("orders", *message.TopicPartition.Topic)
+	s.Equal("value", string(message.Value))
+}
+
+func (s *KafkaTestSuiteTest) Test_RequiresKafkaWith() {
+	topic := uuid.New().String()
+	s.RequiresKafkaWith(testkit.KafkaOptions{
+		Brokers: 3,
+		Topics:  []testkit.TopicSpec{{Name: topic, Partitions: 3, ReplicationFactor: 3}},
+	})
+
+	for _, key := range []string{"first", "second", "third"} {
+		s.Produce(topic, []byte(key), []byte("value"))
+	}
+
+	// librdkafka partitions the keyed messages by the CRC32 of the key
+	for _, key := range []string{"first", "second", "third"} {
+		partition := int32(crc32.ChecksumIEEE([]byte(key)) % 3)
+		s.AssertKafkaPartition(topic, []byte(key), partition, 10*time.Second)
+	}
+}
+
+func (s *KafkaTestSuiteTest) Test_ProducerRetriesInjectedErrors() {
+	topic := uuid.New().String()
+	s.RequiresKafka(topic)
+	s.KafkaInjectErrors(testkit.KafkaAPIProduce, kafka.ErrNotLeaderForPartition, kafka.ErrRequestTimedOut)
+	s.KafkaSetRtt(1, 50*time.Millisecond)
+
+	s.Produce(topic, []byte("key"), []byte("value"))
+
+	message, err := s.WaitForMessage(topic, 10*time.Second)
+	s.Require().NoError(err)
+	s.Equal("value", string(message.Value))
+}
+
+func (s *KafkaTestSuiteTest) Test_ConsumerReconnectsWhenBrokerIsBackUp() {
+	topic := uuid.New().String()
+	s.RequiresKafkaWith(testkit.KafkaOptions{
+		Brokers: 2,
+		Topics:  []testkit.TopicSpec{{Name: topic, Partitions: 2}},
+	})
+	s.Produce(topic, []byte("key"), []byte("value"))
+
+	s.KafkaBrokerDown(1)
+	s.KafkaBrokerUp(1)
+
+	message, err := s.WaitForMessage(topic, 20*time.Second)
+	s.Require().NoError(err)
+	s.Equal("value", string(message.Value))
+}
+
+func (s *KafkaTestSuiteTest) Test_ExpectKafkaMessages() {
+	topic := uuid.New().String()
+	s.RequiresKafka(topic)
+	created := kafka.Header{Key: "type", Value: []byte("created")}
+
+	// When
+	s.Produce(topic, []byte("order-1"), []byte(`{"id":1,"status":"NEW","total":{"amount":10}}`), created)
+	s.Produce(topic, []byte("order-2"), []byte(`{"id":2,"status":"NEW"}`), created)
+	s.Produce(topic, []byte("order-1"), []byte(`{"id":1,"status":"PAID"}`), created)
+	s.Produce(topic, []byte("order-1"), []byte(`{"id":1,"status":"NEW","total":{"amount":10}}`), created)
+
+	// Then
+	got := s.ExpectKafkaMessages(topic).
+		WithKey("order-1").
+		WithHeader("type", "created").
+		WithJSONValueContaining(map[string]any{"status": "NEW", "total": map[string]any{"amount": 10}}).
+		Count(2).
+		Within(5 * time.Second)
+	s.Len(got, 2)
+	for _, message := range got {
+		s.Equal("order-1", string(message.Key))
+	}
+}
+
+func (s *KafkaTestSuiteTest) Test_AssertNoKafkaMessage() {
+	topic := uuid.New().String()
+	s.RequiresKafka(topic)
+
+	// When no message is produced
+
+	// Then
+	s.True(s.AssertNoKafkaMessage(topic, time.Second))
+}
+
+func (s *KafkaTestSuiteTest) Test_AssertNoKafkaMessage_FailsWhenTheConsumerFails() {
+	s.RequiresKafka(uuid.New().String())
+	failures := new(failureRecorder)
+	assertions := s.Assertions
+	s.Assertions = assert.New(failures)
+
+	// When the topic does not exist
+	ok := s.AssertNoKafkaMessage(uuid.New().String(), 10*time.Second)
+
+	// Then
+	s.Assertions = assertions
+	s.False(ok)
+	s.Contains(failures.String(), "Unknown topic or partition")
+}
+
+// failureRecorder records the failures of the assertions instead of failing the test
+type failureRecorder struct {
+	strings.Builder
+}
+
+func (r *failureRecorder) Errorf(format string, args ...any) {
+	_, _ = fmt.Fprintf(r, format, args...)
+}
+
+type order struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+}
+
+func (s *KafkaTestSuiteTest) Test_ProduceTyped_WaitForTyped() {
+	topic := uuid.New().String()
+	s.RequiresKafka(topic)
+	serde := kitkafka.JSON[order]()
+
+	// When
+	testkit.ProduceTyped(s, serde, kitkafka.TypedMessage[order]{
+		Topic:   topic,
+		Key:     "order-1",
+		Value:   order{ID: "order-1", Status: "NEW"},
+		Headers: map[string]string{"type": "created"},
+	})
+
+	// Then
+	got := testkit.WaitForTyped(s, serde, topic, 5*time.Second)
+	s.Equal(topic, got.Topic)
+	s.Equal("order-1", got.Key)
+	s.Equal(order{ID: "order-1", Status: "NEW"}, got.Value)
+	s.Equal(map[string]string{"type": "created"}, got.Headers)
+}
+
+func (s *KafkaTestSuiteTest) Test_ProduceWithSchema_WaitForWithSchema() {
+	topic := uuid.New().String()
+	s.RequiresKafka(topic)
+	s.NotEmpty(s.SchemaRegistryURL())
+
+	schema := `{"type":"record","name":"Order","fields":[{"name":"id","type":"string"},{"name":"status","type":"string"}]}`
+	serde, err := kitkafka.Avro[avroOrder](schema)
+	s.Require().NoError(err)
+
+	// When
+	testkit.ProduceWithSchema(s, serde, topic+"-value", kitkafka.Schema{Type: kitkafka.SchemaTypeAvro, Schema: schema}, kitkafka.TypedMessage[avroOrder]{
+		Topic: topic,
+		Key:   "order-1",
+		Value: avroOrder{ID: "order-1", Status: "NEW"},
+	})
+
+	// Then
+	got := testkit.WaitForWithSchema[avroOrder](s, serde, topic, 5*time.Second)
+	s.Equal(avroOrder{ID: "order-1", Status: "NEW"}, got.Value)
+}
+
+type avroOrder struct {
+	ID     string `avro:"id"`
+	Status string `avro:"status"`
+}
+
+func (s *KafkaTestSuiteTest) Test_ProduceBatch() {
+	topic := uuid.New().String()
+	s.RequiresKafkaWith(testkit.KafkaOptions{Topics: []testkit.TopicSpec{{Name: topic, Partitions: 3}}})
+	messages := make([]kitkafka.Message, 0, 1000)
+	for i := range 1000 {
+		messages = append(messages, kitkafka.Message{Topic: topic, Key: []byte(strconv.Itoa(i)), Value: []byte("value")})
+	}
+
+	// When
+	got := s.ProduceBatch(messages)
+
+	// Then
+	s.Len(got, len(messages))
+	offsets := make(map[int32]kafka.Offset)
+	for i, partition := range got {
+		s.Equal(topic, *partition.Topic)
+		s.NoError(partition.Error)
+		s.Equal(int32(crc32.ChecksumIEEE(messages[i].Key)%3), partition.Partition)
+		s.Equal(offsets[partition.Partition], partition.Offset, "offsets follow the order of the messages")
+		offsets[partition.Partition]++
+	}
+}
+
+func (s *KafkaTestSuiteTest) Test_Transactions() {
+	topic := uuid.New().String()
+	s.RequiresKafka(topic)
+
+	// When
+	s.BeginTx()
+	s.Produce(topic, []byte("aborted"), []byte("value"))
+	s.AbortTx()
+
+	s.BeginTx()
+	s.Produce(topic, []byte("committed"), []byte("value"))
+	s.CommitTx()
+
+	// Then - the mock cluster does not filter the aborted messages, KafkaTransactionsSuiteTest asserts the isolation
+	got := s.ExpectKafkaMessages(topic).WithKey("committed").Within(10 * time.Second)
+	s.Len(got, 1)
+}
+
+type KafkaTransactionsSuiteTest struct {
+	testkit.Suite
+}
+
+func TestKafkaTransactionsSuiteTest(t *testing.T) {
+	// the mock cluster does not hide the aborted messages from the read_committed consumers, the suite uses the
+	// broker of docker-compose.yml
+	const bootstrapServers = "localhost:9094"
+	conn, err := net.DialTimeout("tcp", bootstrapServers, time.Second)
+	if err != nil {
+		t.Skipf("kafka broker is not reachable on %s: %v", bootstrapServers, err)
+	}
+	_ = conn.Close()
+
+	testkit.RunWithConfig(t, new(KafkaTransactionsSuiteTest), testkit.SuiteConfig{
+		Kafka: &testkit.KafkaConfig{
+			Mode:             "broker",
+			BootstrapServers: bootstrapServers,
+			Client:           map[string]string{"isolation.level": "read_committed"},
+		},
+	})
+}
+
+func (s *KafkaTransactionsSuiteTest) Test_ReadCommittedConsumersOnlySeeCommittedMessages() {
+	s.RequiresKafka("orders")
+
+	// When
+	s.BeginTx()
+	s.Produce("orders", []byte("aborted"), []byte("value"))
+	s.AbortTx()
+
+	// Then
+	s.AssertNoKafkaMessage("orders", 10*time.Second)
+
+	// When
+	s.BeginTx()
+	s.Produce("orders", []byte("committed"), []byte("value"))
+	s.CommitTx()
+
+	// Then
+	got := s.ExpectKafkaMessages("orders").Within(10 * time.Second)
+	s.Require().Len(got, 1)
+	s.Equal("committed", string(got[0].Key))
+}
+
+func (s *KafkaTestSuiteTest) Test_KafkaConsumerGroupOffsets() {
+	topic := uuid.New().String()
+	servers := s.RequiresKafkaWith(testkit.KafkaOptions{Topics: []testkit.TopicSpec{{Name: topic, Partitions: 2}}})
+	for i := range 5 {
+		s.Produce(topic, []byte(strconv.Itoa(i)), []byte("value"))
+	}
+
+	s.Contains(s.KafkaTopics(), topic)
+	s.Len(s.DescribeKafkaTopic(topic).Partitions, 2)
+	s.Equal(int64(5), s.KafkaConsumerLag("service", topic).Total())
+	s.Empty(s.KafkaCommittedOffsets("service", topic))
+
+	// When - the service consumes and commits the messages
+	consumer, err := kafka.NewConsumer(&kafka.ConfigMap{
+		"bootstrap.servers":  servers,
+		"group.id":           "service",
+		"auto.offset.reset":  "earliest",
+		"enable.auto.commit": false,
+	})
+	s.Require().NoError(err)
+	defer func() { _ = consumer.Close() }()
+	s.Require().NoError(consumer.Subscribe(topic, nil))
+	for consumed := 0; consumed < 5; {
+		message, err := consumer.ReadMessage(10 * time.Second)
+		s.Require().NoError(err)
+		_, err = consumer.CommitMessage(message)
+		s.Require().NoError(err)
+		consumed++
+	}
+
+	// Then
+	s.True(s.AssertGroupCaughtUp("service", 5*time.Second))
+	committed := s.KafkaCommittedOffsets("service", topic)
+	s.Equal(kafka.Offset(5), committed[0]+committed[1])
+	s.Equal(testkit.KafkaLag{topic: {0: 0, 1: 0}}, s.KafkaConsumerLag("service"))
+}
+
+func (s *KafkaTestSuiteTest) Test_RecordTopics() {
+	topic := uuid.New().String()
+	s.RequiresKafka(topic)
+	recording := s.RecordTopics(topic)
+
+	// When
+	s.Produce(topic, []byte("order-1"), []byte(`{"status":"NEW"}`), kafka.Header{Key: "type", Value: []byte("created")})
+	s.Produce(topic, []byte("order-2"), []byte{0xff, 0xfe})
+
+	// Then
+	path := filepath.Join(s.T().TempDir(), "fixture.jsonl")
+	records := recording.Save(path)
+	s.Require().Len(records, 2)
+	s.Equal(topic, records[0].Topic)
+	s.Equal("order-1", records[0].Key)
+	s.Equal(`{"status":"NEW"}`, records[0].Value)
+	s.Equal([]testkit.KafkaRecordHeader{{Key: "type", Value: "created"}}, records[0].Headers)
+	s.Equal(int64(1), records[1].Offset)
+	s.Equal("base64", records[1].Encoding)
+	s.Equal("//4=", records[1].Value)
+
+	content, err := os.ReadFile(path)
+	s.Require().NoError(err)
+	s.Len(strings.Split(strings.TrimSpace(string(content)), "\n"), 2)
+
+	// Then - the messages produced once the recording stopped are not recorded
+	s.Produce(topic, []byte("order-3"), []byte(`{"status":"NEW"}`))
+	s.ExpectKafkaMessages(topic).WithKey("order-3").Within(10 * time.Second)
+	s.Len(recording.Stop(), 2)
+}
+
+func (s *KafkaTestSuiteTest) Test_ReplayKafkaFixture() {
+	topic := uuid.New().String()
+	s.RequiresKafkaWith(testkit.KafkaOptions{Topics: []testkit.TopicSpec{{Name: topic, Partitions: 2}}})
+
+	// When
+	delivered := s.ReplayKafkaFixture("internal/testdata/kafka-fixture.jsonl", map[string]string{
+		"topic":    topic,
+		"order_id": "order-1",
+	})
+
+	// Then
+	s.Require().Len(delivered, 2)
+	s.Equal(int32(1), delivered[0].Partition)
+	s.Equal(int32(0), delivered[1].Partition)
+
+	got := s.ExpectKafkaMessages(topic).
+		WithKey("order-1").
+		WithHeader("type", "created").
+		WithJSONValueContaining(map[string]any{"id": "order-1"}).
+		Within(5 * time.Second)
+	s.Require().Len(got, 1)
+	s.Equal(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC), got[0].Timestamp.UTC())
+
+	binary := s.ExpectKafkaMessages(topic).WithKey("binary").Within(5 * time.Second)
+	s.Require().Len(binary, 1)
+	s.Equal([]byte{0, 1, 2, 3}, binary[0].Value)
+}
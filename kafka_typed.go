@@ -0,0 +1,50 @@
+package testkit
+
+import (
+	"time"
+
+	"github.com/bdpiprava/testkit/kitkafka"
+)
+
+// ProduceTyped produces the message with the value serialized by the serde to its topic, the test fails when the
+// value cannot be serialized
+//
+//	testkit.ProduceTyped(s, kitkafka.JSON[Order](), kitkafka.TypedMessage[Order]{Topic: "orders", Key: "order-1", Value: order})
+func ProduceTyped[T any](s KafkaSuite, serde kitkafka.Serde[T], message kitkafka.TypedMessage[T]) {
+	s.T().Helper()
+	encoded, err := message.Encode(serde)
+	s.Require().NoError(err, "failed to encode the message to topic %s", message.Topic)
+	s.ProduceMessage(encoded)
+}
+
+// WaitForTyped waits for a message to be consumed from the kafka topic and returns it with the value deserialized
+// by the serde. The test fails when no message is consumed within the timeout or its value cannot be deserialized,
+// the failure includes the raw value of the message
+//
+//	message := testkit.WaitForTyped(s, kitkafka.JSON[Order](), "orders", 5*time.Second)
+func WaitForTyped[T any](s KafkaSuite, serde kitkafka.Serde[T], topic string, timeout time.Duration) *kitkafka.TypedMessage[T] {
+	s.T().Helper()
+	message, err := s.WaitForMessage(topic, timeout)
+	s.Require().NoError(err)
+
+	typed, err := kitkafka.Decode(serde, message)
+	s.Require().NoError(err, "failed to decode the message with key %q from topic %s, raw value: %q", message.Key, topic, message.Value)
+	return typed
+}
+
+// ProduceWithSchema produces the message with the value serialized by the serde in the Confluent wire format, the
+// schema is registered under the subject in the schema registry of the test
+//
+//	testkit.ProduceWithSchema(s, avroSerde, "orders-value", kitkafka.Schema{Type: kitkafka.SchemaTypeAvro, Schema: schema}, message)
+func ProduceWithSchema[T any](s KafkaSuite, serde kitkafka.Serde[T], subject string, schema kitkafka.Schema, message kitkafka.TypedMessage[T]) {
+	s.T().Helper()
+	ProduceTyped(s, kitkafka.Confluent(serde, s.SchemaRegistry(), subject, schema), message)
+}
+
+// WaitForWithSchema waits for a message in the Confluent wire format to be consumed from the kafka topic and returns
+// it with the value deserialized by the serde, the schema id of the message must be registered in the schema registry
+// of the test
+func WaitForWithSchema[T any](s KafkaSuite, serde kitkafka.Serde[T], topic string, timeout time.Duration) *kitkafka.TypedMessage[T] {
+	s.T().Helper()
+	return WaitForTyped(s, kitkafka.Confluent(serde, s.SchemaRegistry(), "", kitkafka.Schema{}), topic, timeout)
+}
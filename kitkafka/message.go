@@ -2,6 +2,8 @@
 package kitkafka
 
 import (
+	"maps"
+	"slices"
 	"time"
 
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
@@ -16,3 +18,57 @@ type Message struct {
 	Timestamp     time.Time
 	TimestampType kafka.TimestampType
 }
+
+// TypedMessage represents a Kafka message with a value of type T, serialized by a Serde
+type TypedMessage[T any] struct {
+	Topic     string
+	Key       string
+	Value     T
+	Headers   map[string]string
+	Timestamp time.Time
+}
+
+// Encode returns the message with the value serialized by the serde
+func (m TypedMessage[T]) Encode(serde Serde[T]) (Message, error) {
+	value, err := serde.Serialize(m.Value)
+	if err != nil {
+		return Message{}, err
+	}
+
+	headers := make([]kafka.Header, 0, len(m.Headers))
+	for _, key := range slices.Sorted(maps.Keys(m.Headers)) {
+		headers = append(headers, kafka.Header{Key: key, Value: []byte(m.Headers[key])})
+	}
+
+	return Message{
+		Topic:     m.Topic,
+		Key:       []byte(m.Key),
+		Value:     value,
+		Headers:   headers,
+		Timestamp: m.Timestamp,
+	}, nil
+}
+
+// Decode returns the message with the value deserialized by the serde, the last value of a repeated header wins
+func Decode[T any](serde Serde[T], message *kafka.Message) (*TypedMessage[T], error) {
+	value, err := serde.Deserialize(message.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := make(map[string]string, len(message.Headers))
+	for _, header := range message.Headers {
+		headers[header.Key] = string(header.Value)
+	}
+
+	typed := &TypedMessage[T]{
+		Key:       string(message.Key),
+		Value:     value,
+		Headers:   headers,
+		Timestamp: message.Timestamp,
+	}
+	if message.TopicPartition.Topic != nil {
+		typed.Topic = *message.TopicPartition.Topic
+	}
+	return typed, nil
+}
@@ -0,0 +1,190 @@
+package kitkafka
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// magicByte is the first byte of the values in the Confluent wire format, followed by the schema id
+const magicByte = 0
+
+// SchemaType is the type of schema registered in the schema registry
+type SchemaType string
+
+// Schema types of the schema registry
+const (
+	SchemaTypeAvro     SchemaType = "AVRO"
+	SchemaTypeProtobuf SchemaType = "PROTOBUF"
+	SchemaTypeJSON     SchemaType = "JSON"
+)
+
+// Schema is a schema of the schema registry
+type Schema struct {
+	Type   SchemaType `json:"schemaType,omitempty"`
+	Schema string     `json:"schema"`
+}
+
+// SchemaRegistryClient is a client of the REST API of a Confluent schema registry
+type SchemaRegistryClient struct {
+	url    string
+	client *http.Client
+}
+
+// NewSchemaRegistryClient returns a client of the schema registry at the url
+func NewSchemaRegistryClient(url string) *SchemaRegistryClient {
+	return &SchemaRegistryClient{url: url, client: http.DefaultClient}
+}
+
+// Register registers the schema under the subject and returns its id
+func (c *SchemaRegistryClient) Register(subject string, schema Schema) (int, error) {
+	var response struct {
+		ID int `json:"id"`
+	}
+
+	err := c.do(http.MethodPost, fmt.Sprintf("/subjects/%s/versions", url.PathEscape(subject)), schema, &response)
+	return response.ID, errors.Wrapf(err, "failed to register schema under subject %s", subject)
+}
+
+// SchemaByID returns the schema with the id
+func (c *SchemaRegistryClient) SchemaByID(id int) (Schema, error) {
+	var schema Schema
+	err := c.do(http.MethodGet, fmt.Sprintf("/schemas/ids/%d", id), nil, &schema)
+	if schema.Type == "" {
+		schema.Type = SchemaTypeAvro
+	}
+	return schema, errors.Wrapf(err, "failed to get schema %d", id)
+}
+
+// do sends the request with the body as JSON and decodes the response into result
+func (c *SchemaRegistryClient) do(method, path string, body, result any) error {
+	var content bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&content).Encode(body); err != nil {
+			return err
+		}
+	}
+
+	req, err := http.NewRequest(method, c.url+path, &content)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/vnd.schemaregistry.v1+json")
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = res.Body.Close() }()
+
+	if res.StatusCode >= http.StatusBadRequest {
+		var apiErr struct {
+			Code    int    `json:"error_code"`
+			Message string `json:"message"`
+		}
+		_ = json.NewDecoder(res.Body).Decode(&apiErr)
+		return errors.Errorf("schema registry returned %d: %s (error code %d)", res.StatusCode, apiErr.Message, apiErr.Code)
+	}
+	return json.NewDecoder(res.Body).Decode(result)
+}
+
+// ConfluentSerde serializes the values with a serde in the Confluent wire format: the magic byte, the id of the schema
+// registered in the schema registry and, for the protobuf schemas, the indexes of the message in the schema
+type ConfluentSerde[T any] struct {
+	serde   Serde[T]
+	client  *SchemaRegistryClient
+	subject string
+	schema  Schema
+
+	mu sync.Mutex
+	id int // id of the schema, registered on the first serialization
+}
+
+// Confluent returns the serde framing the values of the serde in the Confluent wire format, the schema is registered
+// under the subject e.g. orders-value
+func Confluent[T any](serde Serde[T], client *SchemaRegistryClient, subject string, schema Schema) *ConfluentSerde[T] {
+	return &ConfluentSerde[T]{serde: serde, client: client, subject: subject, schema: schema}
+}
+
+// Serialize returns the value serialized by the serde with the Confluent framing, the schema is registered on the
+// first call
+func (s *ConfluentSerde[T]) Serialize(value T) ([]byte, error) {
+	id, err := s.schemaID()
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := s.serde.Serialize(value)
+	if err != nil {
+		return nil, err
+	}
+
+	data := binary.BigEndian.AppendUint32([]byte{magicByte}, uint32(id))
+	if s.schema.Type == SchemaTypeProtobuf {
+		// the indexes of the first message of the schema, [0], are written as a single zero
+		data = append(data, 0)
+	}
+	return append(data, payload...), nil
+}
+
+// Deserialize returns the value of the data in the Confluent wire format deserialized by the serde, the schema id of
+// the data must be registered in the schema registry
+func (s *ConfluentSerde[T]) Deserialize(data []byte) (T, error) {
+	var zero T
+	if len(data) < 5 || data[0] != magicByte {
+		return zero, errors.New("value is not in the Confluent wire format, unknown magic byte")
+	}
+
+	id := int(binary.BigEndian.Uint32(data[1:5]))
+	schema, err := s.client.SchemaByID(id)
+	if err != nil {
+		return zero, err
+	}
+
+	payload := data[5:]
+	if schema.Type == SchemaTypeProtobuf {
+		if payload, err = skipMessageIndexes(payload); err != nil {
+			return zero, err
+		}
+	}
+	return s.serde.Deserialize(payload)
+}
+
+// schemaID returns the id of the schema, registering it under the subject on the first call
+func (s *ConfluentSerde[T]) schemaID() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.id != 0 {
+		return s.id, nil
+	}
+
+	id, err := s.client.Register(s.subject, s.schema)
+	if err != nil {
+		return 0, err
+	}
+	s.id = id
+	return id, nil
+}
+
+// skipMessageIndexes returns the payload after the zigzag varint encoded indexes of the protobuf message
+func skipMessageIndexes(data []byte) ([]byte, error) {
+	count, n := binary.Varint(data)
+	if n <= 0 || count < 0 {
+		return nil, errors.New("invalid protobuf message indexes")
+	}
+
+	data = data[n:]
+	for range count {
+		if _, n = binary.Varint(data); n <= 0 {
+			return nil, errors.New("invalid protobuf message indexes")
+		}
+		data = data[n:]
+	}
+	return data, nil
+}
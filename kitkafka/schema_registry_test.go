@@ -0,0 +1,56 @@
+package kitkafka_test
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/bdpiprava/testkit/internal/schemaregistry"
+	"github.com/bdpiprava/testkit/kitkafka"
+)
+
+func Test_ConfluentSerde(t *testing.T) {
+	server := httptest.NewServer(schemaregistry.New())
+	t.Cleanup(server.Close)
+	client := kitkafka.NewSchemaRegistryClient(server.URL)
+
+	avroSerde, err := kitkafka.Avro[order](orderSchema)
+	require.NoError(t, err)
+	serde := kitkafka.Confluent[order](avroSerde, client, "orders-value", kitkafka.Schema{Type: kitkafka.SchemaTypeAvro, Schema: orderSchema})
+
+	data, err := serde.Serialize(order{ID: "order-1", Amount: 10.5})
+	require.NoError(t, err)
+	require.Equal(t, []byte{0, 0, 0, 0, 1}, data[:5])
+
+	got, err := serde.Deserialize(data)
+	require.NoError(t, err)
+	require.Equal(t, order{ID: "order-1", Amount: 10.5}, got)
+
+	_, err = serde.Deserialize(data[5:])
+	require.EqualError(t, err, "value is not in the Confluent wire format, unknown magic byte")
+
+	_, err = serde.Deserialize([]byte{0, 0, 0, 0, 9})
+	require.ErrorContains(t, err, "failed to get schema 9")
+}
+
+func Test_ConfluentSerde_Protobuf(t *testing.T) {
+	server := httptest.NewServer(schemaregistry.New())
+	t.Cleanup(server.Close)
+
+	schema := kitkafka.Schema{
+		Type:   kitkafka.SchemaTypeProtobuf,
+		Schema: `syntax = "proto3"; message StringValue { string value = 1; }`,
+	}
+	serde := kitkafka.Confluent(kitkafka.Protobuf[*wrapperspb.StringValue](), kitkafka.NewSchemaRegistryClient(server.URL), "names-value", schema)
+
+	data, err := serde.Serialize(wrapperspb.String("order-1"))
+	require.NoError(t, err)
+	require.Equal(t, []byte{0, 0, 0, 0, 1, 0}, data[:6])
+
+	got, err := serde.Deserialize(data)
+	require.NoError(t, err)
+	require.True(t, proto.Equal(wrapperspb.String("order-1"), got))
+}
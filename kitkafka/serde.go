@@ -0,0 +1,98 @@
+package kitkafka
+
+import (
+	"encoding/json"
+
+	"github.com/hamba/avro/v2"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+)
+
+// Serde serializes the values of type T to the value of the kafka messages and back
+type Serde[T any] interface {
+	Serialize(value T) ([]byte, error)
+	Deserialize(data []byte) (T, error)
+}
+
+// JSONSerde serializes the values as JSON
+type JSONSerde[T any] struct{}
+
+// JSON returns the serde of the values serialized as JSON
+func JSON[T any]() Serde[T] {
+	return JSONSerde[T]{}
+}
+
+// Serialize returns the value as JSON
+func (JSONSerde[T]) Serialize(value T) ([]byte, error) {
+	data, err := json.Marshal(value)
+	return data, errors.Wrap(err, "failed to serialize value as JSON")
+}
+
+// Deserialize returns the value of the JSON data
+func (JSONSerde[T]) Deserialize(data []byte) (T, error) {
+	var value T
+	err := json.Unmarshal(data, &value)
+	return value, errors.Wrap(err, "failed to deserialize JSON value")
+}
+
+// ProtobufSerde serializes the protobuf messages in the protobuf wire format, T is the pointer to the generated
+// message type e.g. *orderpb.Order
+type ProtobufSerde[T proto.Message] struct{}
+
+// Protobuf returns the serde of the protobuf messages of type T
+func Protobuf[T proto.Message]() Serde[T] {
+	return ProtobufSerde[T]{}
+}
+
+// Serialize returns the message in the protobuf wire format
+func (ProtobufSerde[T]) Serialize(value T) ([]byte, error) {
+	data, err := proto.Marshal(value)
+	return data, errors.Wrap(err, "failed to serialize protobuf message")
+}
+
+// Deserialize returns the message of the data in the protobuf wire format
+func (ProtobufSerde[T]) Deserialize(data []byte) (T, error) {
+	var zero T
+	value, ok := zero.ProtoReflect().Type().New().Interface().(T)
+	if !ok {
+		return zero, errors.Errorf("failed to create protobuf message %T", zero)
+	}
+
+	if err := proto.Unmarshal(data, value); err != nil {
+		return zero, errors.Wrap(err, "failed to deserialize protobuf message")
+	}
+	return value, nil
+}
+
+// AvroSerde serializes the values in the avro binary encoding of its schema, the fields of T are mapped with the avro
+// struct tags e.g. `avro:"name"`
+type AvroSerde[T any] struct {
+	schema avro.Schema
+}
+
+// Avro returns the serde of the values encoded with the avro schema
+func Avro[T any](schema string) (*AvroSerde[T], error) {
+	parsed, err := avro.Parse(schema)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to parse avro schema")
+	}
+	return &AvroSerde[T]{schema: parsed}, nil
+}
+
+// Schema returns the avro schema of the serde
+func (s *AvroSerde[T]) Schema() avro.Schema {
+	return s.schema
+}
+
+// Serialize returns the value in the avro binary encoding
+func (s *AvroSerde[T]) Serialize(value T) ([]byte, error) {
+	data, err := avro.Marshal(s.schema, value)
+	return data, errors.Wrap(err, "failed to serialize avro value")
+}
+
+// Deserialize returns the value of the data in the avro binary encoding
+func (s *AvroSerde[T]) Deserialize(data []byte) (T, error) {
+	var value T
+	err := avro.Unmarshal(s.schema, data, &value)
+	return value, errors.Wrap(err, "failed to deserialize avro value")
+}
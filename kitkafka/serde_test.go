@@ -0,0 +1,98 @@
+package kitkafka_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/bdpiprava/testkit/kitkafka"
+)
+
+type order struct {
+	ID     string  `json:"id" avro:"id"`
+	Amount float64 `json:"amount" avro:"amount"`
+}
+
+const orderSchema = `{
+  "type": "record",
+  "name": "Order",
+  "fields": [
+    {"name": "id", "type": "string"},
+    {"name": "amount", "type": "double"}
+  ]
+}`
+
+func Test_Serde(t *testing.T) {
+	avroSerde, err := kitkafka.Avro[order](orderSchema)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name  string
+		serde kitkafka.Serde[order]
+	}{
+		{name: "json", serde: kitkafka.JSON[order]()},
+		{name: "avro", serde: avroSerde},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			data, err := tc.serde.Serialize(order{ID: "order-1", Amount: 10.5})
+			require.NoError(t, err)
+
+			got, err := tc.serde.Deserialize(data)
+			require.NoError(t, err)
+			require.Equal(t, order{ID: "order-1", Amount: 10.5}, got)
+
+			_, err = tc.serde.Deserialize([]byte("{"))
+			require.Error(t, err)
+		})
+	}
+}
+
+func Test_ProtobufSerde(t *testing.T) {
+	serde := kitkafka.Protobuf[*wrapperspb.StringValue]()
+
+	data, err := serde.Serialize(wrapperspb.String("order-1"))
+	require.NoError(t, err)
+
+	got, err := serde.Deserialize(data)
+	require.NoError(t, err)
+	require.True(t, proto.Equal(wrapperspb.String("order-1"), got))
+
+	_, err = serde.Deserialize([]byte{0xff})
+	require.Error(t, err)
+}
+
+func Test_TypedMessage_EncodeDecode(t *testing.T) {
+	serde := kitkafka.JSON[order]()
+	timestamp := time.Now().Truncate(time.Millisecond)
+	message := kitkafka.TypedMessage[order]{
+		Topic:     "orders",
+		Key:       "order-1",
+		Value:     order{ID: "order-1", Amount: 10.5},
+		Headers:   map[string]string{"type": "created", "source": "test"},
+		Timestamp: timestamp,
+	}
+
+	encoded, err := message.Encode(serde)
+	require.NoError(t, err)
+	require.Equal(t, []kafka.Header{
+		{Key: "source", Value: []byte("test")},
+		{Key: "type", Value: []byte("created")},
+	}, encoded.Headers)
+
+	topic := encoded.Topic
+	got, err := kitkafka.Decode(serde, &kafka.Message{
+		TopicPartition: kafka.TopicPartition{Topic: &topic},
+		Key:            encoded.Key,
+		Value:          encoded.Value,
+		Headers:        encoded.Headers,
+		Timestamp:      timestamp,
+	})
+	require.NoError(t, err)
+	require.Equal(t, &message, got)
+}
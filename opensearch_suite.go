@@ -13,24 +13,93 @@ import (
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 
+	"github.com/bdpiprava/testkit/internal"
 	"github.com/bdpiprava/testkit/search"
 )
 
+const openSearchProviderName = "opensearch"
+
+func init() {
+	RegisterProvider(&openSearchProvider{})
+}
+
+// openSearchProvider provides the opensearch client to the tests
+type openSearchProvider struct {
+	client *opensearch.Client
+}
+
 // openSearch is a wrapper around the elasticsearch client
 type openSearch struct {
+	noopRelease
 	client *opensearch.Client
 	log    logrus.FieldLogger
+	ctx    func() context.Context // ctx returns the context of the running test, the client may be shared by the tests
 }
 
 // RequireOpenSearch returns the opensearch client
 func (s *Suite) RequireOpenSearch() SearchClient {
-	if osClient == nil {
-		s.T().Fatalf("opensearch client is not initialized")
+	if s.config == nil || s.config.OpenSearch == nil {
+		s.T().Skip("testkit: skipped, opensearch is not configured")
+	}
+
+	client := *s.AcquireResource(openSearchProviderName, "", nil).(*openSearch)
+	client.ctx = s.GetContext
+	return &client
+}
+
+// Name returns the name of the provider
+func (p *openSearchProvider) Name() string {
+	return openSearchProviderName
+}
+
+// Init initializes the opensearch client from the opensearch section of the config file
+func (p *openSearchProvider) Init(config ConfigSection, _ logrus.FieldLogger) (err error) {
+	if !config.Exists() {
+		return nil
+	}
+
+	var cfg internal.ElasticSearchConfig
+	if err = config.Decode(&cfg); err != nil {
+		return err
 	}
+
+	p.client, err = opensearch.NewClient(opensearch.Config{
+		Addresses: strings.Split(cfg.Addresses, ","),
+		Username:  cfg.Username,
+		Password:  cfg.Password,
+	})
+
+	return err
+}
+
+// Acquire returns the client bound to the logger of the test
+func (p *openSearchProvider) Acquire(_ context.Context, request AcquireRequest) (Resource, error) {
+	if p.client == nil {
+		return nil, errors.New("opensearch client is not initialized")
+	}
+
 	return &openSearch{
-		client: osClient,
-		log:    s.l,
+		client: p.client,
+		log:    request.Logger,
+	}, nil
+}
+
+// HealthCheck returns an error unless the health of the opensearch cluster is yellow or green
+func (p *openSearchProvider) HealthCheck(ctx context.Context) error {
+	if p.client == nil {
+		return errors.New("opensearch client is not initialized")
+	}
+
+	res, err := p.client.Cluster.Health(p.client.Cluster.Health.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer closeSilently(res.Body)
+
+	if res.IsError() {
+		return errors.Errorf("opensearch cluster health failed: %s", res.String())
 	}
+	return checkClusterHealth(res.Body)
 }
 
 // CreateIndex creates a new index
@@ -52,8 +121,7 @@ func (s *openSearch) CreateIndex(index string, settings search.CreateIndexSettin
 	}
 
 	log.Debug("executing create index request")
-	ctx := context.Background()
-	resp, err := req.Do(ctx, s.client)
+	resp, err := req.Do(s.ctx(), s.client)
 	if err != nil {
 		log.Debug("failed to execute create index request")
 		return err
@@ -74,7 +142,7 @@ func (s *openSearch) CloseIndices(indices ...string) {
 		"indices": indices,
 	})
 	log.Debug("closing indices")
-	resp, err := s.client.Indices.Close(indices)
+	resp, err := s.client.Indices.Close(indices, s.client.Indices.Close.WithContext(s.ctx()))
 	if err != nil {
 		log.Debug("failed to close indices")
 		return
@@ -115,7 +183,7 @@ func (s *openSearch) FindIndices(pattern string) (search.Indices, error) {
 
 	log.Debug("finding indices")
 	resp, err := s.client.Cat.Indices(
-		s.client.Cat.Indices.WithContext(context.Background()),
+		s.client.Cat.Indices.WithContext(s.ctx()),
 		s.client.Cat.Indices.WithIndex(pattern),
 		s.client.Cat.Indices.WithFormat("json"),
 	)
@@ -143,6 +211,7 @@ func (s *openSearch) GetIndexSettings(index string) (search.IndexSetting, error)
 
 	log.Debug("getting index settings")
 	resp, err := s.client.Indices.GetSettings(
+		s.client.Indices.GetSettings.WithContext(s.ctx()),
 		s.client.Indices.GetSettings.WithIndex(index),
 	)
 	if err != nil {
@@ -182,7 +251,7 @@ func (s *openSearch) DeleteIndices(pattern string) error {
 	}
 
 	log.Debugf("deleting indices: %v", list)
-	_, err = s.client.Indices.Delete(list)
+	_, err = s.client.Indices.Delete(list, s.client.Indices.Delete.WithContext(s.ctx()))
 	return err
 }
 
@@ -194,7 +263,7 @@ func (s *openSearch) DeleteByQuery(indices []string, query string) error {
 	})
 
 	log.Debug("deleting by query")
-	resp, err := s.client.DeleteByQuery(indices, strings.NewReader(query))
+	resp, err := s.client.DeleteByQuery(indices, strings.NewReader(query), s.client.DeleteByQuery.WithContext(s.ctx()))
 	if err != nil {
 		log.Debug("failed to delete by query")
 		return errors.Wrapf(err, "failed to delete by query")
@@ -221,6 +290,7 @@ func (s *openSearch) SearchByQuery(index string, query string) (search.QueryResp
 	log.Debug("searching by query")
 	var result search.QueryResponse
 	resp, err := s.client.Search(
+		s.client.Search.WithContext(s.ctx()),
 		s.client.Search.WithIndex(index),
 		s.client.Search.WithBody(strings.NewReader(query)),
 	)
@@ -257,6 +327,7 @@ func (s *openSearch) CreateDocument(index, docID string, document map[string]any
 	resp, err := s.client.Index(
 		index,
 		bytes.NewReader(content),
+		s.client.Index.WithContext(s.ctx()),
 		s.client.Index.WithRefresh("true"),
 		s.client.Index.WithDocumentID(docID),
 	)
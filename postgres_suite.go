@@ -1,16 +1,30 @@
 package testkit
 
 import (
+	"context"
 	"fmt"
 	"strings"
-	"time"
+	"testing"
 
+	"github.com/google/uuid"
 	"github.com/jmoiron/sqlx"
 	_ "github.com/lib/pq" // postgres driver
+	"github.com/sirupsen/logrus"
 
 	"github.com/bdpiprava/testkit/internal"
 )
 
+const postgresProviderName = "postgres"
+
+func init() {
+	RegisterProvider(&postgresProvider{})
+}
+
+// postgresProvider provides a fresh database to each test requiring a postgres database
+type postgresProvider struct {
+	helper *internal.PostgresDB
+}
+
 type psqlDataHolder struct {
 	generatedName string
 	actualName    string
@@ -18,62 +32,116 @@ type psqlDataHolder struct {
 	helper        *internal.PostgresDB
 }
 
+// Release closes the connection and deletes the database
+func (h *psqlDataHolder) Release() error {
+	if h.db == nil {
+		return nil
+	}
+
+	_ = h.db.Close()
+	return h.helper.Delete(h.generatedName)
+}
+
 var errDBNotInitiated = fmt.Errorf("database not initiated, must call RequiresPostgresDatabase before using this method")
 
-// RequiresPostgresDatabase is a helper function to get the test database based on configuration
-func (s *Suite) RequiresPostgresDatabase(name string) *sqlx.DB {
-	var err error
-	ctx := s.GetContext()
-	postgresDB, err := internal.NewPostgresDB(suiteConfig.PostgresConfig)
-	s.Require().NoError(err)
+// Name returns the name of the provider
+func (p *postgresProvider) Name() string {
+	return postgresProviderName
+}
+
+// Init initializes the provider from the postgres section of the config file
+func (p *postgresProvider) Init(config ConfigSection, _ logrus.FieldLogger) (err error) {
+	var cfg internal.PostgresConfig
+	if err = config.Decode(&cfg); err != nil {
+		return err
+	}
+
+	p.helper, err = internal.NewPostgresDB(cfg)
+	return err
+}
 
-	generatedName := s.generateDatabaseName(name)
-	db, err := postgresDB.CreateDatabase(ctx, generatedName, s.Logger())
-	s.Require().NoError(err)
+// Acquire creates a database named after the requested name
+func (p *postgresProvider) Acquire(ctx context.Context, request AcquireRequest) (Resource, error) {
+	generatedName := generateDatabaseName(request.Name)
+	db, err := p.helper.CreateDatabase(ctx, generatedName, request.Logger)
+	if err != nil {
+		return nil, err
+	}
 
-	dataHolder := psqlDataHolder{
+	return &psqlDataHolder{
 		generatedName: generatedName,
-		actualName:    name,
-		helper:        postgresDB,
+		actualName:    request.Name,
+		helper:        p.helper,
 		db:            db,
-	}
-	s.postgresDBs[s.T().Name()] = dataHolder
+	}, nil
+}
+
+// HealthCheck pings the postgres server
+func (p *postgresProvider) HealthCheck(ctx context.Context) error {
+	return p.helper.Ping(ctx)
+}
 
-	return db
+// RequiresPostgresDatabase is a helper function to get the test database based on configuration, the database is
+// visible to the running test and its subtests and deleted when the suite completes
+func (s *Suite) RequiresPostgresDatabase(name string) *sqlx.DB {
+	return s.requiresPostgresDatabase(s.scopeOwner(ScopeSuite), s.T(), name)
 }
 
-// cleanDatabase delete the database instance
-func (s *Suite) cleanDatabase() {
-	for _, holder := range s.postgresDBs {
-		if holder.db == nil {
-			continue
-		}
+// RequiresPostgresDatabaseIn creates a database for the scope, the database is deleted when the test owning
+// the scope completes
+func (s *Suite) RequiresPostgresDatabaseIn(scope Scope, name string) *sqlx.DB {
+	owner := s.scopeOwner(scope)
+	return s.requiresPostgresDatabase(owner, owner, name)
+}
 
-		_ = holder.db.Close()
-		_ = holder.helper.Delete(holder.generatedName)
+// requiresPostgresDatabase creates a database visible to the test and its subtests, the database is deleted when
+// the owner completes
+func (s *Suite) requiresPostgresDatabase(owner, test *testing.T, name string) *sqlx.DB {
+	if !isPostgresConfigured(s.config) {
+		s.T().Skip("testkit: skipped, postgres is not configured")
 	}
+
+	dataHolder := s.acquireResource(owner, test, postgresProviderName, name, nil).(*psqlDataHolder)
+	s.suiteStats().recordResource(test, "postgres.database", dataHolder.generatedName)
+	return dataHolder.db
+}
+
+// maxIdentifierLength is the length postgres truncates the identifiers to
+const maxIdentifierLength = 63
+
+// generateDatabaseName generates a unique name with the given prefix, the suffix is a random uuid so the
+// databases of concurrent tests and test processes do not collide. The prefix is trimmed to keep the name within
+// the identifier length of postgres
+func generateDatabaseName(prefix string) string {
+	suffix := strings.ReplaceAll(uuid.NewString(), "-", "")
+	prefix = prefix[:min(len(prefix), maxIdentifierLength-len(suffix)-1)]
+	return strings.ToLower(fmt.Sprintf("%s_%s", prefix, suffix))
 }
 
-// generateName generates a name with the given prefix and a timestamp
-func (s *Suite) generateDatabaseName(prefix string) string {
-	return strings.ToLower(fmt.Sprintf("%s_%d", prefix, time.Now().UnixMilli()))
+// lookupDatabase returns the database of the current test, or of the parent tests and the suite when recursive
+func (s *Suite) lookupDatabase(recursive bool) (*psqlDataHolder, error) {
+	if resource, ok := s.lookupResource(postgresProviderName, recursive); ok {
+		return resource.(*psqlDataHolder), nil
+	}
+	return nil, errDBNotInitiated
 }
 
 // PsqlDB returns the database instance for the current test
 // if initiated else returns error
 func (s *Suite) PsqlDB() (*sqlx.DB, error) {
-	if dataHolder, ok := s.postgresDBs[s.T().Name()]; ok {
-		return dataHolder.db, nil
+	dataHolder, err := s.lookupDatabase(false)
+	if err != nil {
+		return nil, err
 	}
-	return nil, errDBNotInitiated
+	return dataHolder.db, nil
 }
 
 // PsqlDSN returns the database connection string for the current test db
 // if initiated else returns error
 func (s *Suite) PsqlDSN() (string, error) {
-	dataHolder, ok := s.postgresDBs[s.T().Name()]
-	if !ok {
-		return "", errDBNotInitiated
+	dataHolder, err := s.lookupDatabase(false)
+	if err != nil {
+		return "", err
 	}
 
 	return dataHolder.helper.DSN(dataHolder.generatedName), nil
@@ -85,17 +153,11 @@ func (s *Suite) PsqlDSN() (string, error) {
 // If SubSubTestOne is trying to access the database, it will first check if it has a database
 // if not then it will check SubTestOne and then TestOne
 func (s *Suite) PsqlDBRecursively() (*sqlx.DB, error) {
-	testName := s.T().Name()
-	parts := strings.Split(testName, "/")
-
-	for i := len(parts); i >= 0; i-- {
-		name := strings.Join(parts[0:i], "/")
-		if dataHolder, ok := s.postgresDBs[name]; ok {
-			return dataHolder.db, nil
-		}
+	dataHolder, err := s.lookupDatabase(true)
+	if err != nil {
+		return nil, err
 	}
-
-	return nil, errDBNotInitiated
+	return dataHolder.db, nil
 }
 
 // PsqlDSNRecursively returns the database connection string starting from current test to parent tests
@@ -104,15 +166,10 @@ func (s *Suite) PsqlDBRecursively() (*sqlx.DB, error) {
 // If SubSubTestOne is trying to access the database, it will first check if it has a database
 // if not then it will check SubTestOne and then TestOne
 func (s *Suite) PsqlDSNRecursively() (string, error) {
-	testName := s.T().Name()
-	parts := strings.Split(testName, "/")
-
-	for i := len(parts); i >= 0; i-- {
-		name := strings.Join(parts[0:i], "/")
-		if dataHolder, ok := s.postgresDBs[name]; ok {
-			return dataHolder.helper.DSN(dataHolder.generatedName), nil
-		}
+	dataHolder, err := s.lookupDatabase(true)
+	if err != nil {
+		return "", err
 	}
 
-	return "", errDBNotInitiated
+	return dataHolder.helper.DSN(dataHolder.generatedName), nil
 }
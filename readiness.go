@@ -0,0 +1,99 @@
+package testkit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+
+	"github.com/bdpiprava/testkit/internal"
+)
+
+// minCheckTimeout is the minimum duration of a health check, the last check may outlive the readiness timeout
+const minCheckTimeout = time.Second
+
+// backendStatus is the result of the wait for the backend of a provider to be ready
+type backendStatus struct {
+	backend  string
+	attempts int
+	waited   time.Duration
+	err      error // err is the error of the last health check, nil when the backend is ready
+}
+
+// waitUntilReady checks the health of the provider until it succeeds or the timeout elapses, the delay between two
+// checks starts at the interval and doubles after each failed check up to the maximum interval
+func waitUntilReady(name string, provider Provider, backoff internal.BackoffConfig, log logrus.FieldLogger) backendStatus {
+	start := time.Now()
+	deadline := start.Add(backoff.Timeout)
+	interval := backoff.Interval
+	status := backendStatus{backend: name}
+	for {
+		status.attempts++
+		ctx, cancel := context.WithTimeout(context.Background(), max(time.Until(deadline), minCheckTimeout))
+		status.err = provider.HealthCheck(ctx)
+		cancel()
+		status.waited = time.Since(start)
+
+		if status.err == nil {
+			log.Debugf("%s is ready after %d attempts", name, status.attempts)
+			return status
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return status
+		}
+
+		log.WithError(status.err).Infof("waiting for %s to be ready, attempt %d", name, status.attempts)
+		time.Sleep(min(interval, remaining))
+		interval = min(interval*2, backoff.MaxInterval)
+	}
+}
+
+// readinessError returns an error with the status of each backend if any of them is not ready, nil otherwise
+func readinessError(statuses []backendStatus) error {
+	for _, status := range statuses {
+		if status.err != nil {
+			return errors.Errorf("backends are not ready:\n%s", statusTable(statuses))
+		}
+	}
+	return nil
+}
+
+// statusTable returns the status of the backends as a table
+func statusTable(statuses []backendStatus) string {
+	var sb strings.Builder
+	w := tabwriter.NewWriter(&sb, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "BACKEND\tSTATUS\tATTEMPTS\tWAITED\tLAST ERROR")
+	for _, status := range statuses {
+		state, lastErr := "ready", ""
+		if status.err != nil {
+			state, lastErr = "not ready", status.err.Error()
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", status.backend, state, status.attempts, status.waited.Round(time.Millisecond), lastErr)
+	}
+	_ = w.Flush()
+	return sb.String()
+}
+
+// checkClusterHealth returns an error unless the status of the cluster health response is yellow or green
+func checkClusterHealth(body io.Reader) error {
+	var health struct {
+		Status string `json:"status"`
+	}
+
+	if err := json.NewDecoder(body).Decode(&health); err != nil {
+		return errors.Wrap(err, "failed to decode cluster health")
+	}
+
+	if health.Status != "yellow" && health.Status != "green" {
+		return errors.Errorf("cluster health is %q", health.Status)
+	}
+	return nil
+}
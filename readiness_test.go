@@ -0,0 +1,42 @@
+package testkit_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bdpiprava/testkit"
+)
+
+type ReadinessSuiteTest struct {
+	testkit.Suite
+}
+
+func TestReadinessSuiteTest(t *testing.T) {
+	var checks atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/__admin/mappings" && checks.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+
+	testkit.RunWithConfig(t, new(ReadinessSuiteTest), testkit.SuiteConfig{
+		APIMockConfig: &testkit.APIMockConfig{Address: server.URL},
+		Readiness: testkit.ReadinessConfig{
+			BackoffConfig: testkit.BackoffConfig{Timeout: 5 * time.Second, Interval: 10 * time.Millisecond},
+		},
+	})
+
+	require.EqualValues(t, 3, checks.Load())
+}
+
+func (s *ReadinessSuiteTest) Test_Ready() {
+	s.NotEmpty(s.Config().APIMockConfig.Address)
+}
@@ -0,0 +1,280 @@
+package testkit
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	reportFormatJUnit = "junit"
+	reportFormatJSON  = "json"
+)
+
+var (
+	reportDir     = flag.String("testkit.report", "", "directory to write the reports of the suites to, reports are disabled when empty")
+	reportFormats = flag.String("testkit.report.format", "junit,json", "comma separated list of the report formats to write: junit, json")
+)
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name       string           `xml:"name,attr"`
+	Tests      int              `xml:"tests,attr"`
+	Failures   int              `xml:"failures,attr"`
+	Errors     int              `xml:"errors,attr"`
+	Skipped    int              `xml:"skipped,attr"`
+	Time       string           `xml:"time,attr"`
+	Timestamp  string           `xml:"timestamp,attr"`
+	Properties *junitProperties `xml:"properties,omitempty"`
+	TestCases  []junitTestCase  `xml:"testcase"`
+}
+
+type junitProperties struct {
+	Properties []junitProperty `xml:"property"`
+}
+
+type junitProperty struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value,attr"`
+}
+
+type junitTestCase struct {
+	ClassName  string           `xml:"classname,attr"`
+	Name       string           `xml:"name,attr"`
+	Time       string           `xml:"time,attr"`
+	Properties *junitProperties `xml:"properties,omitempty"`
+	Failure    *junitFailure    `xml:"failure,omitempty"`
+	Error      *junitFailure    `xml:"error,omitempty"`
+	Skipped    *struct{}        `xml:"skipped,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Content string `xml:",chardata"`
+}
+
+type jsonSuiteReport struct {
+	Name       string           `json:"name"`
+	Start      time.Time        `json:"start"`
+	End        time.Time        `json:"end"`
+	DurationMS int64            `json:"duration_ms"`
+	Resources  []jsonResource   `json:"resources,omitempty"`
+	Tests      []jsonTestReport `json:"tests"`
+}
+
+type jsonTestReport struct {
+	Name       string         `json:"name"`
+	Status     string         `json:"status"`
+	Start      time.Time      `json:"start"`
+	End        time.Time      `json:"end"`
+	DurationMS int64          `json:"duration_ms"`
+	Failures   []string       `json:"failures,omitempty"`
+	Panic      string         `json:"panic,omitempty"`
+	Resources  []jsonResource `json:"resources,omitempty"`
+	Attempts   int            `json:"attempts,omitempty"`
+	Flaky      bool           `json:"flaky,omitempty"`
+}
+
+type jsonResource struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+// writeReports writes the reports of the suite in the formats requested by the -testkit.report.format flag
+func writeReports(testName, suiteName string, stats *SuiteInformation) error {
+	dir := strings.TrimSpace(*reportDir)
+	if dir == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return errors.Wrapf(err, "failed to create report directory %s", dir)
+	}
+
+	base := filepath.Join(dir, testNameSanitizer.ReplaceAllString(testName, "_"))
+	for _, format := range strings.Split(*reportFormats, ",") {
+		var content []byte
+		var err error
+		var path string
+		switch strings.TrimSpace(format) {
+		case reportFormatJUnit:
+			path = base + ".xml"
+			content, err = junitReport(suiteName, stats)
+		case reportFormatJSON:
+			path = base + ".json"
+			content, err = jsonReport(suiteName, stats)
+		default:
+			return fmt.Errorf("unsupported report format: %s", format)
+		}
+
+		if err != nil {
+			return errors.Wrapf(err, "failed to generate %s report", format)
+		}
+
+		if err = os.WriteFile(path, content, 0600); err != nil {
+			return errors.Wrapf(err, "failed to write report %s", path)
+		}
+	}
+	return nil
+}
+
+// junitReport returns the JUnit XML report of the suite
+func junitReport(suiteName string, stats *SuiteInformation) ([]byte, error) {
+	suite := junitTestSuite{
+		Name:       suiteName,
+		Time:       formatSeconds(stats.End.Sub(stats.Start)),
+		Timestamp:  stats.Start.Format(time.RFC3339),
+		Properties: junitResourceProperties(stats.Resources),
+	}
+
+	for _, info := range sortedTestStats(stats) {
+		testCase := junitTestCase{
+			ClassName:  suiteName,
+			Name:       info.TestName,
+			Time:       formatSeconds(info.Duration()),
+			Properties: junitResourceProperties(info.Resources),
+		}
+
+		if info.Attempts > 0 {
+			testCase.Properties = appendProperty(testCase.Properties, "testkit.attempts", strconv.Itoa(info.Attempts))
+			testCase.Properties = appendProperty(testCase.Properties, "testkit.flaky", strconv.FormatBool(info.Flaky()))
+		}
+
+		switch {
+		case info.Panic != "":
+			suite.Errors++
+			message, _, _ := strings.Cut(info.Panic, "\n")
+			testCase.Error = &junitFailure{Message: message, Type: "panic", Content: info.Panic}
+		case !info.Passed:
+			suite.Failures++
+			testCase.Failure = &junitFailure{Message: failureMessage(info), Type: "failure", Content: strings.Join(info.Failures, "\n")}
+		case info.Skipped:
+			suite.Skipped++
+			testCase.Skipped = &struct{}{}
+		}
+
+		suite.Tests++
+		suite.TestCases = append(suite.TestCases, testCase)
+	}
+
+	content, err := xml.MarshalIndent(junitTestSuites{Suites: []junitTestSuite{suite}}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), content...), nil
+}
+
+// jsonReport returns the JSON report of the suite
+func jsonReport(suiteName string, stats *SuiteInformation) ([]byte, error) {
+	report := jsonSuiteReport{
+		Name:       suiteName,
+		Start:      stats.Start,
+		End:        stats.End,
+		DurationMS: stats.End.Sub(stats.Start).Milliseconds(),
+		Resources:  jsonResources(stats.Resources),
+		Tests:      make([]jsonTestReport, 0, len(stats.TestStats)),
+	}
+
+	for _, info := range sortedTestStats(stats) {
+		status := "passed"
+		switch {
+		case !info.Passed:
+			status = "failed"
+		case info.Skipped:
+			status = "skipped"
+		}
+
+		report.Tests = append(report.Tests, jsonTestReport{
+			Name:       info.TestName,
+			Status:     status,
+			Start:      info.Start,
+			End:        info.End,
+			DurationMS: info.Duration().Milliseconds(),
+			Failures:   info.Failures,
+			Panic:      info.Panic,
+			Resources:  jsonResources(info.Resources),
+			Attempts:   info.Attempts,
+			Flaky:      info.Flaky(),
+		})
+	}
+
+	return json.MarshalIndent(report, "", "  ")
+}
+
+// sortedTestStats returns the stats of the tests in the order they started
+func sortedTestStats(stats *SuiteInformation) []*TestInformation {
+	result := make([]*TestInformation, 0, len(stats.TestStats))
+	for _, info := range stats.TestStats {
+		result = append(result, info)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Start.Equal(result[j].Start) {
+			return result[i].TestName < result[j].TestName
+		}
+		return result[i].Start.Before(result[j].Start)
+	})
+	return result
+}
+
+// failureMessage returns the summary of the first failure, the error line for the failures reported by the assertions.
+// Failed tests without failures failed in a subtest
+func failureMessage(info *TestInformation) string {
+	if len(info.Failures) == 0 {
+		return "test failed"
+	}
+
+	lines := strings.Split(strings.TrimSpace(info.Failures[0]), "\n")
+	for _, line := range lines {
+		if message, ok := strings.CutPrefix(strings.TrimSpace(line), "Error:"); ok {
+			return strings.TrimSpace(message)
+		}
+	}
+	return lines[0]
+}
+
+func junitResourceProperties(resources []TestResource) *junitProperties {
+	if len(resources) == 0 {
+		return nil
+	}
+
+	properties := &junitProperties{}
+	for _, resource := range resources {
+		properties.Properties = append(properties.Properties, junitProperty{Name: resource.Kind, Value: resource.Name})
+	}
+	return properties
+}
+
+func appendProperty(properties *junitProperties, name, value string) *junitProperties {
+	if properties == nil {
+		properties = &junitProperties{}
+	}
+	properties.Properties = append(properties.Properties, junitProperty{Name: name, Value: value})
+	return properties
+}
+
+func jsonResources(resources []TestResource) []jsonResource {
+	result := make([]jsonResource, 0, len(resources))
+	for _, resource := range resources {
+		result = append(result, jsonResource(resource))
+	}
+	return result
+}
+
+func formatSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
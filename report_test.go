@@ -0,0 +1,64 @@
+package testkit_test
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bdpiprava/testkit"
+)
+
+type ReportSuiteTest struct {
+	testkit.Suite
+}
+
+func TestReportSuiteTest(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, flag.Set("testkit.report", dir))
+	// reports are written in the cleanup of the suite, which runs before the cleanups registered earlier
+	t.Cleanup(func() {
+		require.NoError(t, flag.Set("testkit.report", ""))
+
+		content, err := os.ReadFile(filepath.Join(dir, "TestReportSuiteTest.json"))
+		require.NoError(t, err)
+
+		var report map[string]any
+		require.NoError(t, json.Unmarshal(content, &report))
+		require.Equal(t, "ReportSuiteTest", report["name"])
+
+		statuses := make(map[string]string)
+		for _, test := range report["tests"].([]any) {
+			test := test.(map[string]any)
+			statuses[test["name"].(string)] = test["status"].(string)
+		}
+		require.Equal(t, map[string]string{
+			"Test_Passed":         "passed",
+			"Test_Passed/subtest": "passed",
+			"Test_Skipped":        "skipped",
+		}, statuses)
+
+		content, err = os.ReadFile(filepath.Join(dir, "TestReportSuiteTest.xml"))
+		require.NoError(t, err)
+		require.Contains(t, string(content), `<testsuite name="ReportSuiteTest" tests="3" failures="0" errors="0" skipped="1"`)
+		require.Contains(t, string(content), `<property name="kafka.bootstrap_servers"`)
+	})
+
+	testkit.Run(t, new(ReportSuiteTest))
+}
+
+func (s *ReportSuiteTest) Test_Passed() {
+	s.Run("subtest", func() {
+		servers := s.RequiresKafka(uuid.New().String())
+		s.False(strings.TrimSpace(servers) == "")
+	})
+}
+
+func (s *ReportSuiteTest) Test_Skipped() {
+	s.T().Skip("skipped to be reported")
+}
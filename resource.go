@@ -0,0 +1,365 @@
+package testkit
+
+import (
+	"context"
+	"maps"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v3"
+
+	"github.com/bdpiprava/testkit/internal"
+)
+
+// mu guards the resources shared between the suite and its copies running the parallel tests
+var mu sync.RWMutex
+
+var (
+	providersMu sync.RWMutex
+	providers   = make(map[string]*registeredProvider)
+)
+
+// Resource is a resource acquired from a provider by a test e.g. a database, a cluster or a client
+type Resource interface {
+	// Release releases the resource, it is called once the test which acquired it is torn down
+	Release() error
+}
+
+// Provider provides a kind of resource to the suites, it is registered with RegisterProvider
+type Provider interface {
+	// Name returns the name of the provider, it is the key of the section of the config file passed to Init
+	Name() string
+
+	// Init initializes the provider from its section of the config file, it is called once before the first suite runs
+	Init(config ConfigSection, log logrus.FieldLogger) error
+
+	// Acquire acquires a resource for the test
+	Acquire(ctx context.Context, request AcquireRequest) (Resource, error)
+
+	// HealthCheck returns an error when the backend of the provider is not reachable
+	HealthCheck(ctx context.Context) error
+}
+
+// AcquireRequest is the request of a test to acquire a resource from a provider
+type AcquireRequest struct {
+	TestName string             // TestName is the name of the test acquiring the resource
+	Name     string             // Name is the name of the resource requested by the test e.g. the database name
+	Options  any                // Options are the provider specific options e.g. the topics to create
+	Logger   logrus.FieldLogger // Logger is the logger of the test
+}
+
+// ConfigSection is the section of the config file of a provider
+type ConfigSection struct {
+	name  string
+	value any
+}
+
+// Name returns the key of the section in the config file
+func (c ConfigSection) Name() string {
+	return c.name
+}
+
+// Exists returns true if the section is present in the config file
+func (c ConfigSection) Exists() bool {
+	return c.value != nil
+}
+
+// Decode decodes the section into the value pointed by out, it is left unchanged when the section does not exist
+func (c ConfigSection) Decode(out any) error {
+	if !c.Exists() {
+		return nil
+	}
+
+	content, err := yaml.Marshal(c.value)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read config section %s", c.name)
+	}
+	return errors.Wrapf(yaml.Unmarshal(content, out), "failed to decode config section %s", c.name)
+}
+
+type registeredProvider struct {
+	provider Provider
+	once     sync.Once
+	status   *backendStatus // status is the readiness of the backend, nil when the provider is not configured
+	err      error
+}
+
+// RegisterProvider registers the provider, it panics if a provider with the same name is already registered.
+// The providers must be registered before the first suite runs, usually in an init function
+func RegisterProvider(provider Provider) {
+	providersMu.Lock()
+	defer providersMu.Unlock()
+
+	name := provider.Name()
+	if _, ok := providers[name]; ok {
+		panic("testkit: RegisterProvider called twice for provider " + name)
+	}
+	providers[name] = &registeredProvider{provider: provider}
+}
+
+// initialiseProviders initializes the registered providers with their section of the config file once, and returns
+// the providers initialized for the suite whose section of the suite config differs from the config file. The
+// registered providers whose section is overridden by the suite are not initialized with the config file. The
+// providers are initialized concurrently and wait for their backend to be ready when their section exists
+func initialiseProviders(sections map[string]any, readiness internal.ReadinessConfig, log logrus.FieldLogger) (map[string]Provider, error) {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+
+	names := slices.Collect(maps.Keys(providers))
+	sort.Strings(names)
+
+	suiteProviders := make(map[string]Provider)
+	for _, name := range names {
+		if reflect.DeepEqual(sections[name], fileSections[name]) {
+			continue
+		}
+
+		provider, err := newProvider(providers[name].provider)
+		if err != nil {
+			return nil, err
+		}
+		suiteProviders[name] = provider
+	}
+
+	initialised := make([]*registeredProvider, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if provider, ok := suiteProviders[name]; ok {
+				section := ConfigSection{name: name, value: sections[name]}
+				status, err := initialiseProvider(provider, section, readiness, log)
+				initialised[i] = &registeredProvider{provider: provider, status: status, err: err}
+				return
+			}
+
+			registered := providers[name]
+			registered.once.Do(func() {
+				section := ConfigSection{name: name, value: fileSections[name]}
+				registered.status, registered.err = initialiseProvider(registered.provider, section, readiness, log)
+			})
+			initialised[i] = registered
+		}()
+	}
+	wg.Wait()
+
+	statuses := make([]backendStatus, 0, len(names))
+	for i, registered := range initialised {
+		if registered.err != nil {
+			return nil, errors.Wrapf(registered.err, "failed to initialise provider %s", names[i])
+		}
+
+		if registered.status != nil {
+			statuses = append(statuses, *registered.status)
+		}
+	}
+	return suiteProviders, readinessError(statuses)
+}
+
+// initialiseProvider initializes the provider with its section and waits for its backend to be ready when the
+// section exists
+func initialiseProvider(provider Provider, section ConfigSection, readiness internal.ReadinessConfig, log logrus.FieldLogger) (*backendStatus, error) {
+	log = log.WithField("provider", section.name)
+	if err := provider.Init(section, log); err != nil {
+		return nil, err
+	}
+
+	if !section.Exists() {
+		return nil, nil
+	}
+
+	status := waitUntilReady(section.name, provider, readiness.For(section.name), log)
+	return &status, nil
+}
+
+// newProvider returns a copy of the registered provider to initialize with the section of the suite config, the copy
+// keeps the fields the registered provider was built with
+func newProvider(provider Provider) (Provider, error) {
+	typ := reflect.TypeOf(provider)
+	if typ.Kind() != reflect.Pointer || typ.Elem().Kind() != reflect.Struct {
+		return nil, errors.Errorf("provider %s can not be configured per suite, it must be a pointer to a struct", provider.Name())
+	}
+	clone := reflect.New(typ.Elem())
+	clone.Elem().Set(reflect.ValueOf(provider).Elem())
+	return clone.Interface().(Provider), nil
+}
+
+// providerOf returns the provider of the suite with the given name, the registered provider unless the suite
+// overrides its config
+func (s *Suite) providerOf(name string) (Provider, error) {
+	if provider, ok := s.providers[name]; ok {
+		return provider, nil
+	}
+
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+
+	registered, ok := providers[name]
+	if !ok {
+		return nil, errors.Errorf("provider %s is not registered", name)
+	}
+	return registered.provider, nil
+}
+
+// providerNames returns the names of the registered providers, their sections are accepted in the config file
+func providerNames() []string {
+	providersMu.RLock()
+	defer providersMu.RUnlock()
+	return slices.Sorted(maps.Keys(providers))
+}
+
+// acquiredResource is a resource acquired by a test
+type acquiredResource struct {
+	provider string
+	resource Resource
+}
+
+// AcquireResource acquires a resource from the provider for the current test or subtest, it is released when the
+// test completes. The test fails when the provider fails to acquire the resource
+func (s *Suite) AcquireResource(provider, name string, options any) Resource {
+	return s.AcquireResourceIn(ScopeSubtest, provider, name, options)
+}
+
+// AcquireResourceIn acquires a resource from the provider for the scope, it is released when the test owning the
+// scope completes. The test fails when the provider fails to acquire the resource
+func (s *Suite) AcquireResourceIn(scope Scope, provider, name string, options any) Resource {
+	owner := s.scopeOwner(scope)
+	return s.acquireResource(owner, owner, provider, name, options)
+}
+
+// acquireResource acquires a resource visible to the test and its subtests, the resource is released when the
+// owner completes
+func (s *Suite) acquireResource(owner, test *testing.T, provider, name string, options any) Resource {
+	p, err := s.providerOf(provider)
+	s.Require().NoError(err)
+
+	resource, err := p.Acquire(s.GetContext(), AcquireRequest{
+		TestName: test.Name(),
+		Name:     name,
+		Options:  options,
+		Logger:   s.Logger().WithFields(logrus.Fields{"provider": provider, "owner": owner.Name()}),
+	})
+	s.Require().NoError(err, "failed to acquire resource from provider %s", provider)
+
+	key := test.Name()
+	mu.Lock()
+	s.resources[key] = append(s.resources[key], acquiredResource{provider: provider, resource: resource})
+	mu.Unlock()
+
+	owner.Cleanup(func() {
+		s.releaseResource(key, resource)
+	})
+	return resource
+}
+
+// Resource returns the last resource of the provider acquired by the current test, when the test did not acquire one
+// the resources of the parent tests and of the suite are looked up.
+// In case of, TestOne -> TestOne/SubTestOne -> TestOne/SubTestOne/SubSubTestOne
+// If SubSubTestOne is looking up a resource, it will first check if it has one
+// if not then it will check SubTestOne and then TestOne
+func (s *Suite) Resource(provider string) (Resource, bool) {
+	return s.lookupResource(provider, true)
+}
+
+// lookupResource returns the last resource of the provider acquired by the current test, or by its parent tests
+// and the suite when recursive
+func (s *Suite) lookupResource(provider string, recursive bool) (Resource, bool) {
+	return s.lookupResourceOf(s.T().Name(), provider, recursive)
+}
+
+// lookupResourceOf returns the last resource of the provider acquired by the test, or by its parent tests
+// and the suite when recursive
+func (s *Suite) lookupResourceOf(testName, provider string, recursive bool) (Resource, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	name := testName
+	for {
+		acquired := s.resources[name]
+		for i := len(acquired) - 1; i >= 0; i-- {
+			if acquired[i].provider == provider {
+				return acquired[i].resource, true
+			}
+		}
+
+		idx := strings.LastIndex(name, "/")
+		if !recursive || idx <= 0 {
+			return nil, false
+		}
+		name = name[:idx]
+	}
+}
+
+// releaseResource releases the resource acquired by the test unless it is already released
+func (s *Suite) releaseResource(testName string, resource Resource) {
+	mu.Lock()
+	acquired := s.resources[testName]
+	idx := slices.IndexFunc(acquired, func(a acquiredResource) bool { return a.resource == resource })
+	if idx < 0 {
+		mu.Unlock()
+		return
+	}
+
+	provider := acquired[idx].provider
+	s.resources[testName] = slices.Delete(acquired, idx, idx+1)
+	if len(s.resources[testName]) == 0 {
+		delete(s.resources, testName)
+	}
+	mu.Unlock()
+
+	if err := resource.Release(); err != nil {
+		s.Logger().WithError(err).Warnf("failed to release resource of provider %s", provider)
+	}
+}
+
+// releaseResources releases the resources owned by the test in the reverse order they were acquired,
+// the resources of the subtests are released before the ones of their parent test
+func (s *Suite) releaseResources(testName string) {
+	mu.Lock()
+	keys := slices.Collect(maps.Keys(s.resources))
+	sort.Strings(keys)
+	owned := make([]acquiredResource, 0)
+	for _, key := range keys {
+		if !ownedBy(key, testName) {
+			continue
+		}
+
+		owned = append(owned, s.resources[key]...)
+		delete(s.resources, key)
+	}
+	mu.Unlock()
+
+	for i := len(owned) - 1; i >= 0; i-- {
+		if err := owned[i].resource.Release(); err != nil {
+			s.Logger().WithError(err).Warnf("failed to release resource of provider %s", owned[i].provider)
+		}
+	}
+}
+
+// forkResources returns a copy of the resources visible to a copy of the suite, the copy does not share the slices
+// so the resources acquired by the copy are not visible to the suite
+func (s *Suite) forkResources() map[string][]acquiredResource {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	result := make(map[string][]acquiredResource, len(s.resources))
+	for key, acquired := range s.resources {
+		result[key] = slices.Clone(acquired)
+	}
+	return result
+}
+
+// noopRelease is embedded by the resources which have nothing to release
+type noopRelease struct{}
+
+// Release does nothing
+func (noopRelease) Release() error {
+	return nil
+}
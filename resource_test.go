@@ -0,0 +1,170 @@
+package testkit_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bdpiprava/testkit"
+)
+
+var (
+	counters             = &counterProvider{name: "counter"}
+	subtestCounters      = &counterProvider{name: "subtest-counter"}
+	testCounters         = &counterProvider{name: "test-counter"}
+	suiteCounters        = &counterProvider{name: "suite-counter"}
+	subtestSuiteCounters = &counterProvider{name: "subtest-suite-counter"}
+
+	// scopedCounters are the providers of ScopeSuiteTest, one per test so the tests do not depend on their order
+	scopedCounters = []*counterProvider{subtestCounters, testCounters, suiteCounters, subtestSuiteCounters}
+)
+
+func init() {
+	testkit.RegisterProvider(counters)
+	for _, provider := range scopedCounters {
+		testkit.RegisterProvider(provider)
+	}
+}
+
+// counterProvider provides counters which count the number of times they were released
+type counterProvider struct {
+	name     string
+	acquired atomic.Int32
+	released atomic.Int32
+}
+
+type counter struct {
+	name     string
+	provider *counterProvider
+}
+
+func (c *counter) Release() error {
+	c.provider.released.Add(1)
+	return nil
+}
+
+func (p *counterProvider) Name() string {
+	return p.name
+}
+
+func (p *counterProvider) Init(_ testkit.ConfigSection, _ logrus.FieldLogger) error {
+	return nil
+}
+
+func (p *counterProvider) Acquire(_ context.Context, request testkit.AcquireRequest) (testkit.Resource, error) {
+	p.acquired.Add(1)
+	return &counter{name: request.Name, provider: p}, nil
+}
+
+func (p *counterProvider) HealthCheck(_ context.Context) error {
+	return nil
+}
+
+// countsOf returns the number of resources the providers acquired and released
+func countsOf(providers ...*counterProvider) (acquired, released int32) {
+	for _, provider := range providers {
+		acquired += provider.acquired.Load()
+		released += provider.released.Load()
+	}
+	return acquired, released
+}
+
+type ResourceSuiteTest struct {
+	testkit.Suite
+}
+
+func TestResourceSuiteTest(t *testing.T) {
+	acquired, released := countsOf(counters)
+	testkit.Run(t, new(ResourceSuiteTest))
+
+	acquiredAfter, releasedAfter := countsOf(counters)
+	require.Equal(t, acquired+2, acquiredAfter)
+	require.Equal(t, released+2, releasedAfter)
+}
+
+func (s *ResourceSuiteTest) Test_ShouldLookupResourceRecursively() {
+	s.AcquireResource("counter", "parent", nil)
+
+	s.Run("inherited", func() {
+		resource, ok := s.Resource("counter")
+		s.True(ok)
+		s.Equal("parent", resource.(*counter).name)
+	})
+
+	s.Run("acquired", func() {
+		s.AcquireResource("counter", "child", nil)
+		resource, ok := s.Resource("counter")
+		s.True(ok)
+		s.Equal("child", resource.(*counter).name)
+	})
+}
+
+func (s *ResourceSuiteTest) Test_ShouldNotFindResourceOfOtherTests() {
+	_, ok := s.Resource("counter")
+	s.False(ok)
+}
+
+type ScopeSuiteTest struct {
+	testkit.Suite
+}
+
+func TestScopeSuiteTest(t *testing.T) {
+	acquired, released := countsOf(scopedCounters...)
+	testkit.Run(t, new(ScopeSuiteTest))
+
+	acquiredAfter, releasedAfter := countsOf(scopedCounters...)
+	require.Equal(t, acquired+4, acquiredAfter)
+	require.Equal(t, released+4, releasedAfter)
+}
+
+func (s *ScopeSuiteTest) SetupSuite() {
+	s.AcquireResourceIn(testkit.ScopeSuite, suiteCounters.name, "suite", nil)
+}
+
+func (s *ScopeSuiteTest) Test_ShouldReleaseSubtestScopeWhenSubtestCompletes() {
+	released := subtestCounters.released.Load()
+	s.Run("subtest", func() {
+		s.AcquireResourceIn(testkit.ScopeSubtest, subtestCounters.name, "subtest", nil)
+	})
+
+	_, ok := s.Resource(subtestCounters.name)
+	s.False(ok)
+	s.Equal(released+1, subtestCounters.released.Load())
+}
+
+func (s *ScopeSuiteTest) Test_ShouldShareTestScopeWithSubtests() {
+	released := testCounters.released.Load()
+	s.Run("first", func() {
+		s.AcquireResourceIn(testkit.ScopeTest, testCounters.name, "test", nil)
+	})
+
+	s.Run("second", func() {
+		resource, ok := s.Resource(testCounters.name)
+		s.True(ok)
+		s.Equal("test", resource.(*counter).name)
+	})
+	s.Equal(released, testCounters.released.Load())
+}
+
+func (s *ScopeSuiteTest) Test_ShouldAcquireSuiteScopeFromSubtest() {
+	released := subtestSuiteCounters.released.Load()
+	s.Run("subtest", func() {
+		s.AcquireResourceIn(testkit.ScopeSuite, subtestSuiteCounters.name, "suite", nil)
+	})
+
+	resource, ok := s.Resource(subtestSuiteCounters.name)
+	s.True(ok)
+	s.Equal("suite", resource.(*counter).name)
+	s.Equal(released, subtestSuiteCounters.released.Load())
+}
+
+func (s *ScopeSuiteTest) Test_ShouldShareSuiteScopeWithTests() {
+	released := suiteCounters.released.Load()
+	resource, ok := s.Resource(suiteCounters.name)
+	s.True(ok)
+	s.Equal("suite", resource.(*counter).name)
+	s.Equal(released, suiteCounters.released.Load())
+}
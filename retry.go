@@ -0,0 +1,118 @@
+package testkit
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+var retries = flag.Int("testkit.retries", 0, "number of times a failed test method is retried")
+
+// attempt is the state of an attempt to run a test method which is retried on failure
+type attempt struct {
+	mu     sync.Mutex
+	final  bool // final attempt reports the failures to the test
+	failed bool
+}
+
+// retryable is implemented by the suites which can retry a failed test method
+type retryable interface {
+	startAttempt(t *testing.T, final bool) *attempt
+	currentAttempt() *attempt
+}
+
+// soft returns true if the failures of the attempt are only logged as the test method will be retried
+func (a *attempt) soft() bool {
+	return a != nil && !a.final
+}
+
+// fail marks the attempt as failed
+func (a *attempt) fail() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.failed = true
+}
+
+// hasFailed returns true if a failure was reported during the attempt
+func (a *attempt) hasFailed() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.failed
+}
+
+// runWithRetries runs the test method in a subtest per attempt until an attempt passes or the retries are exhausted.
+// The failures of the attempts before the last one are logged and the attempt is reported as skipped, only the
+// failures reported through the assertions of the suite, panics and timeouts are retried
+func runWithRetries(t *testing.T, suite TestingSuite, suiteName string, method reflect.Method, retries int) {
+	stats := statsOf(suite)
+	defer stats.start(t)()
+
+	for i := 1; i <= retries+1; i++ {
+		final := i > retries
+		var current *attempt
+		t.Run(fmt.Sprintf("attempt_%d", i), func(t *testing.T) {
+			parentT := suite.T()
+			defer suite.SetT(parentT)
+
+			current = startAttempt(t, suite, final)
+			defer func() {
+				if !current.hasFailed() {
+					return
+				}
+
+				t.Logf("testkit: attempt %d of %d failed, retrying", i, retries+1)
+				if !t.Skipped() {
+					t.SkipNow()
+				}
+			}()
+
+			suite.SetT(t)
+			runTestMethod(t, suite, suiteName, method)
+		})
+
+		stats.update(t, func(info *TestInformation) {
+			info.Attempts = i
+		})
+
+		// the attempt did not run when it is filtered out by -run
+		if final || current == nil || !current.hasFailed() {
+			return
+		}
+	}
+}
+
+// startAttempt starts an attempt to run the test method, when supported by the suite
+func startAttempt(t *testing.T, suite TestingSuite, final bool) *attempt {
+	if r, ok := suite.(retryable); ok {
+		return r.startAttempt(t, final)
+	}
+	return &attempt{final: final}
+}
+
+// attemptOf returns the current attempt of the suite, nil if the test method is not retried
+func attemptOf(suite TestingSuite) *attempt {
+	if r, ok := suite.(retryable); ok {
+		return r.currentAttempt()
+	}
+	return nil
+}
+
+// retriesOf returns the number of times the failed test method is retried
+func retriesOf(suite TestingSuite, methodName string) int {
+	if policy, ok := suite.(RetryPolicy); ok {
+		if n, ok := policy.Retries()[methodName]; ok {
+			return n
+		}
+	}
+
+	if *retries > 0 {
+		return *retries
+	}
+
+	if config := configOf(suite); config != nil {
+		return config.Retries
+	}
+	return 0
+}
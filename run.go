@@ -7,12 +7,14 @@ import (
 	"regexp"
 	"runtime/debug"
 	"testing"
+	"time"
 )
 
 // Run runs the suite
 func Run(t *testing.T, suite TestingSuite) {
 	defer recoverAndFailOnPanic(t)
 
+	suite.SetS(suite)
 	if once, ok := suite.(OnlyOnce); ok {
 		if err := once.DoOnce(t); err != nil {
 			t.Fatalf("error running test suite: %v", err)
@@ -20,7 +22,42 @@ func Run(t *testing.T, suite TestingSuite) {
 	}
 
 	suite.SetT(t)
-	suite.SetS(suite)
+	enterScope(suite, ScopeSuite, t)
+
+	suiteName := reflect.TypeOf(suite).Elem().Name()
+	stats := newSuiteInformation(t.Name())
+	if recorder, ok := suite.(statsRecorder); ok {
+		recorder.setStats(stats)
+	}
+
+	// cleanup runs once all the tests completed, including the parallel ones
+	t.Cleanup(func() {
+		stats.End = time.Now()
+		for _, info := range sortedTestStats(stats) {
+			if info.Flaky() {
+				t.Logf("testkit: %s passed after %d attempts", info.TestName, info.Attempts)
+			}
+		}
+
+		if handler, ok := suite.(WithStats); ok {
+			handler.HandleStats(suiteName, stats)
+		}
+
+		if err := writeReports(t.Name(), suiteName, stats); err != nil {
+			t.Errorf("testkit: %v", err)
+		}
+	})
+
+	parallel := isParallel(suite)
+	timeout := testTimeout(suite)
+	tags := parseTagFilter(*matchTags)
+	seed, shuffleTests, err := shuffleSeed()
+	if err != nil {
+		_, _ = fmt.Fprintf(os.Stderr, "testkit: %s\n", err)
+
+		//nolint:gocritic
+		os.Exit(1)
+	}
 
 	var suiteSetupDone bool
 	tests := make([]testing.InternalTest, 0)
@@ -36,7 +73,20 @@ func Run(t *testing.T, suite TestingSuite) {
 			os.Exit(1)
 		}
 
-		if !ok {
+		methodTags := tagsOf(suite, method.Name)
+		if !ok || !tags.matches(methodTags) {
+			continue
+		}
+
+		// the tests requiring a backend which is not configured are skipped without setting up the suite
+		if backend := missingBackend(methodTags, configOf(suite)); backend != "" {
+			tests = append(tests, testing.InternalTest{
+				Name: method.Name,
+				F: func(t *testing.T) {
+					defer statsOf(suite).start(t)()
+					t.Skipf("testkit: skipped, %s is not configured", backend)
+				},
+			})
 			continue
 		}
 
@@ -50,45 +100,178 @@ func Run(t *testing.T, suite TestingSuite) {
 		test := testing.InternalTest{
 			Name: method.Name,
 			F: func(t *testing.T) {
+				if parallel {
+					runIsolated(t, suite, suiteName, method, timeout)
+					return
+				}
+
+				defer startTestContext(t, suite, timeout)()
+				if n := retriesOf(suite, method.Name); n > 0 {
+					runWithRetries(t, suite, suiteName, method, n)
+					return
+				}
+
 				parentT := suite.T()
-				defer func(t *testing.T) {
-					t.Helper()
-					r := recover()
-					if tearDownTestSuite, ok := suite.(TearDownTest); ok {
-						tearDownTestSuite.TearDownTest()
-					}
-
-					suite.SetT(parentT)
-					failOnPanic(t, r)
-				}(parentT)
-				defer recoverAndFailOnPanic(t)
+				defer suite.SetT(parentT)
 
 				suite.SetT(t)
-				if setupTestSuite, ok := suite.(SetupTest); ok {
-					setupTestSuite.SetupTest()
-				}
-				method.Func.Call([]reflect.Value{reflect.ValueOf(suite)})
+				runTestMethod(t, suite, suiteName, method)
 			},
 		}
 		tests = append(tests, test)
 	}
 
 	if suiteSetupDone {
-		defer func() {
+		tearDown := func() {
 			if tearDownSuite, ok := suite.(TearDownSuite); ok {
 				tearDownSuite.TearDownSuite()
 			}
-		}()
+		}
+
+		// parallel tests resume only after Run returns, so the suite must be torn down once they complete
+		if parallel {
+			t.Cleanup(tearDown)
+		} else {
+			defer tearDown()
+		}
+	}
+
+	if shuffleTests {
+		t.Logf("testkit: running the tests in shuffled order, reproduce with -testkit.shuffle=%d", seed)
+		tests = shuffled(seed, t.Name(), tests)
 	}
 
 	runTests(t, tests)
 }
+
+// runTestMethod runs the test method wrapped with the per test lifecycle hooks of the suite
+func runTestMethod(t *testing.T, suite TestingSuite, suiteName string, method reflect.Method) {
+	defer statsOf(suite).start(t)()
+	defer enterScope(suite, ScopeTest, t)()
+	defer func() {
+		r := recover()
+		if afterTest, ok := suite.(AfterTest); ok {
+			afterTest.AfterTest(suiteName, method.Name)
+		}
+
+		if tearDownTestSuite, ok := suite.(TearDownTest); ok {
+			tearDownTestSuite.TearDownTest()
+		}
+		failOnSuitePanic(t, suite, r)
+	}()
+
+	if setupTestSuite, ok := suite.(SetupTest); ok {
+		setupTestSuite.SetupTest()
+	}
+
+	if beforeTest, ok := suite.(BeforeTest); ok {
+		beforeTest.BeforeTest(suiteName, method.Name)
+	}
+	method.Func.Call([]reflect.Value{reflect.ValueOf(suite)})
+}
+
+// runSubTest runs the subtest function wrapped with the per subtest lifecycle hooks of the suite,
+// SetupTest and TearDownTest are used for the suites which do not implement the subtest hooks
+func runSubTest(t *testing.T, suite TestingSuite, subtest func()) {
+	defer statsOf(suite).start(t)()
+	defer func() {
+		r := recover()
+		if tearDownSubTest, ok := suite.(TearDownSubTest); ok {
+			tearDownSubTest.TearDownSubTest()
+		} else if tearDownTest, ok := suite.(TearDownTest); ok {
+			tearDownTest.TearDownTest()
+		}
+		failOnSuitePanic(t, suite, r)
+	}()
+
+	if setupSubTest, ok := suite.(SetupSubTest); ok {
+		setupSubTest.SetupSubTest()
+	} else if setupTest, ok := suite.(SetupTest); ok {
+		setupTest.SetupTest()
+	}
+	subtest()
+}
+
+// runIsolated runs the test method in parallel against an isolated copy of the suite
+func runIsolated(t *testing.T, suite TestingSuite, suiteName string, method reflect.Method, timeout time.Duration) {
+	isolated := isolate(t, cloneSuite(suite))
+	t.Parallel()
+	defer startTestContext(t, isolated, timeout)()
+
+	if n := retriesOf(isolated, method.Name); n > 0 {
+		runWithRetries(t, isolated, suiteName, method, n)
+		return
+	}
+	runTestMethod(t, isolated, suiteName, method)
+}
+
+// cloneSuite returns a shallow copy of the suite, the copy shares the fields of the user defined suite.
+// The suite must not be used concurrently while it is copied
+func cloneSuite(suite TestingSuite) TestingSuite {
+	value := reflect.ValueOf(suite)
+	clone := reflect.New(value.Elem().Type())
+	clone.Elem().Set(value.Elem())
+	return clone.Interface().(TestingSuite)
+}
+
+// isolate binds the copy of the suite to the given test, the copy owns its T, assertions, context, logger and resources
+func isolate(t *testing.T, clone TestingSuite) TestingSuite {
+	if f, ok := clone.(forkable); ok {
+		f.fork(t)
+	}
+	clone.SetT(t)
+	clone.SetS(clone)
+	return clone
+}
+
+// startTestContext derives the context of the test when supported by the suite and returns a function to cancel it
+func startTestContext(t *testing.T, suite TestingSuite, timeout time.Duration) func() {
+	if starter, ok := suite.(testContextStarter); ok {
+		return starter.startTestContext(t, timeout)
+	}
+	return func() {}
+}
+
+// testTimeout returns the maximum duration of each test in the suite, zero if there is no limit
+func testTimeout(suite TestingSuite) time.Duration {
+	if timeout, ok := suite.(TestTimeout); ok {
+		return timeout.Timeout()
+	}
+
+	if config := configOf(suite); config != nil {
+		return config.TestTimeout
+	}
+	return 0
+}
+
+// isParallel returns true if the suite opted in to run the test methods in parallel
+func isParallel(suite TestingSuite) bool {
+	p, ok := suite.(ParallelSuite)
+	return ok && p.Parallel()
+}
 func recoverAndFailOnPanic(t *testing.T) {
 	t.Helper()
 	r := recover()
 	failOnPanic(t, r)
 }
 
+// failOnSuitePanic records the panic in the stats of the suite and fails the test,
+// the panic is only logged when the test method will be retried
+func failOnSuitePanic(t *testing.T, suite TestingSuite, r any) {
+	t.Helper()
+	if r == nil {
+		return
+	}
+
+	statsOf(suite).recordPanic(t, r)
+	if current := attemptOf(suite); current.soft() {
+		current.fail()
+		t.Logf("test panicked: %v\n%s", r, debug.Stack())
+		return
+	}
+	failOnPanic(t, r)
+}
+
 func failOnPanic(t *testing.T, r interface{}) {
 	t.Helper()
 	if r != nil {
@@ -0,0 +1,186 @@
+package testkit_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+
+	"github.com/bdpiprava/testkit"
+)
+
+type ParallelSuiteTest struct {
+	testkit.Suite
+	suiteLevelTopic string
+}
+
+func TestParallelSuiteTest(t *testing.T) {
+	testkit.Run(t, new(ParallelSuiteTest))
+}
+
+func (s *ParallelSuiteTest) Parallel() bool {
+	return true
+}
+
+func (s *ParallelSuiteTest) SetupSuite() {
+	s.suiteLevelTopic = uuid.New().String()
+	s.RequiresKafka(s.suiteLevelTopic)
+}
+
+func (s *ParallelSuiteTest) Test_ShouldIsolateTestState_First() {
+	s.assertIsolated("Test_ShouldIsolateTestState_First")
+}
+
+func (s *ParallelSuiteTest) Test_ShouldIsolateTestState_Second() {
+	s.assertIsolated("Test_ShouldIsolateTestState_Second")
+}
+
+func (s *ParallelSuiteTest) Test_ShouldShareSuiteLevelResources() {
+	key := uuid.New().String()
+	s.Produce(s.suiteLevelTopic, []byte(key), []byte("value"))
+
+	got, err := s.WaitForMessage(s.suiteLevelTopic, 5*time.Second)
+
+	s.Require().NoError(err)
+	s.Equal(key, string(got.Key))
+}
+
+func (s *ParallelSuiteTest) assertIsolated(name string) {
+	topic := uuid.New().String()
+	s.RequiresKafka(topic)
+	s.Produce(topic, []byte(name), []byte("value"))
+
+	got, err := s.WaitForMessage(topic, 5*time.Second)
+
+	s.Require().NoError(err)
+	s.Equal(name, string(got.Key))
+	s.True(strings.HasSuffix(s.T().Name(), "/"+name))
+}
+
+type TestContextSuiteTest struct {
+	testkit.Suite
+}
+
+func TestTestContextSuiteTest(t *testing.T) {
+	testkit.Run(t, new(TestContextSuiteTest))
+}
+
+func (s *TestContextSuiteTest) Timeout() time.Duration {
+	return time.Minute
+}
+
+func (s *TestContextSuiteTest) Test_ShouldDeriveContextWithDeadline() {
+	deadline, ok := s.GetContext().Deadline()
+
+	s.True(ok)
+	s.WithinDuration(time.Now().Add(time.Minute), deadline, 5*time.Second)
+}
+
+func (s *TestContextSuiteTest) Test_ShouldCancelSubtestContextOnCompletion() {
+	var ctx context.Context
+	s.Run("subtest", func() {
+		ctx = s.GetContext()
+		s.NoError(ctx.Err())
+	})
+
+	s.ErrorIs(ctx.Err(), context.Canceled)
+	s.NoError(s.GetContext().Err())
+}
+
+type LifecycleSuiteTest struct {
+	testkit.Suite
+	calls []string
+	stats *testkit.SuiteInformation
+}
+
+func TestLifecycleSuiteTest(t *testing.T) {
+	s := new(LifecycleSuiteTest)
+	// stats are handled in the cleanup of the suite, which runs before the cleanups registered earlier
+	t.Cleanup(func() {
+		require.Equal(t, []string{
+			"SetupTest",
+			"BeforeTest LifecycleSuiteTest Test_Lifecycle",
+			"SetupSubTest",
+			"subtest",
+			"TearDownSubTest",
+			"AfterTest LifecycleSuiteTest Test_Lifecycle",
+			"TearDownTest",
+		}, s.calls)
+		require.Len(t, s.stats.TestStats, 2)
+		require.True(t, s.stats.TestStats["Test_Lifecycle"].Passed)
+		require.True(t, s.stats.TestStats["Test_Lifecycle/subtest"].Passed)
+		require.False(t, s.stats.TestStats["Test_Lifecycle/subtest"].End.IsZero())
+	})
+
+	testkit.Run(t, s)
+}
+
+func (s *LifecycleSuiteTest) SetupTest() {
+	s.calls = append(s.calls, "SetupTest")
+}
+
+func (s *LifecycleSuiteTest) TearDownTest() {
+	s.calls = append(s.calls, "TearDownTest")
+}
+
+func (s *LifecycleSuiteTest) SetupSubTest() {
+	s.calls = append(s.calls, "SetupSubTest")
+}
+
+func (s *LifecycleSuiteTest) TearDownSubTest() {
+	s.calls = append(s.calls, "TearDownSubTest")
+}
+
+func (s *LifecycleSuiteTest) BeforeTest(suiteName, testName string) {
+	s.calls = append(s.calls, "BeforeTest "+suiteName+" "+testName)
+}
+
+func (s *LifecycleSuiteTest) AfterTest(suiteName, testName string) {
+	s.calls = append(s.calls, "AfterTest "+suiteName+" "+testName)
+}
+
+func (s *LifecycleSuiteTest) HandleStats(_ string, stats *testkit.SuiteInformation) {
+	s.stats = stats
+}
+
+func (s *LifecycleSuiteTest) Test_Lifecycle() {
+	s.Run("subtest", func() {
+		s.calls = append(s.calls, "subtest")
+	})
+}
+
+type RetrySuiteTest struct {
+	testkit.Suite
+	attempts int
+	stats    *testkit.SuiteInformation
+}
+
+func TestRetrySuiteTest(t *testing.T) {
+	s := new(RetrySuiteTest)
+	// stats are handled in the cleanup of the suite, which runs before the cleanups registered earlier
+	t.Cleanup(func() {
+		require.Equal(t, 2, s.attempts)
+		info := s.stats.TestStats["Test_ShouldPassOnRetry"]
+		require.True(t, info.Passed)
+		require.Equal(t, 2, info.Attempts)
+		require.True(t, info.Flaky())
+	})
+
+	testkit.Run(t, s)
+}
+
+func (s *RetrySuiteTest) Retries() map[string]int {
+	return map[string]int{"Test_ShouldPassOnRetry": 2}
+}
+
+func (s *RetrySuiteTest) HandleStats(_ string, stats *testkit.SuiteInformation) {
+	s.stats = stats
+}
+
+func (s *RetrySuiteTest) Test_ShouldPassOnRetry() {
+	s.attempts++
+	s.Require().Greater(s.attempts, 1, "fails on the first attempt")
+}
@@ -0,0 +1,77 @@
+package testkit
+
+import "testing"
+
+// Scope is the lifetime of a resource, the resource is released when the test owning its scope completes
+type Scope int
+
+const (
+	// ScopeSuite resources are visible to all the tests of the suite and released when the suite completes
+	ScopeSuite Scope = iota
+	// ScopeTest resources are visible to the test method and its subtests and released when the test method completes
+	ScopeTest
+	// ScopeSubtest resources are visible to the running test or subtest and released when it completes
+	ScopeSubtest
+)
+
+// String returns the name of the scope
+func (s Scope) String() string {
+	switch s {
+	case ScopeSuite:
+		return "suite"
+	case ScopeTest:
+		return "test"
+	case ScopeSubtest:
+		return "subtest"
+	default:
+		return "unknown"
+	}
+}
+
+// scopeTracker is implemented by the suites which track the tests owning the scopes of the resources
+type scopeTracker interface {
+	enterScope(scope Scope, t *testing.T) func()
+}
+
+// enterScope sets the test owning the scope and returns a function restoring the previous one
+func (s *Suite) enterScope(scope Scope, t *testing.T) func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	owner := &s.testT
+	if scope == ScopeSuite {
+		owner = &s.suiteT
+	}
+
+	previous := *owner
+	*owner = t
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		*owner = previous
+	}
+}
+
+// scopeOwner returns the test owning the scope, the running test owns the scopes which are not entered yet
+// e.g. ScopeTest in SetupSuite
+func (s *Suite) scopeOwner(scope Scope) *testing.T {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	switch {
+	case scope == ScopeSuite && s.suiteT != nil:
+		return s.suiteT
+	case scope == ScopeTest && s.testT != nil:
+		return s.testT
+	default:
+		return s.t
+	}
+}
+
+// enterScope sets the test owning the scope when supported by the suite and returns a function restoring the previous one
+func enterScope(suite TestingSuite, scope Scope, t *testing.T) func() {
+	if tracker, ok := suite.(scopeTracker); ok {
+		return tracker.enterScope(scope, t)
+	}
+	return func() {}
+}
@@ -36,6 +36,24 @@ func TestOpenSearchSuiteTest(t *testing.T) {
 	testkit.Run(t, new(OpenSearchSuiteTest))
 }
 
+func (s *OpenSearchSuiteTest) Tags() map[string][]string {
+	return map[string][]string{
+		"Test_SearchClientUsesTheContextOfTheRunningTest": {testkit.TagRequiresOpenSearch},
+	}
+}
+
+func (s *OpenSearchSuiteTest) Test_SearchClientUsesTheContextOfTheRunningTest() {
+	var client testkit.SearchClient
+	s.Run("acquire", func() {
+		client = s.RequireOpenSearch()
+	})
+
+	s.Run("use", func() {
+		_, err := client.FindIndices("*")
+		s.NoError(err)
+	})
+}
+
 func (s *OpenSearchSuiteTest) Test_SearchClient() {
 	testCases := []struct {
 		name   string
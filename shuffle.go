@@ -0,0 +1,58 @@
+package testkit
+
+import (
+	"flag"
+	"hash/fnv"
+	"math/rand"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	shuffleOn  = "on"
+	shuffleOff = "off"
+)
+
+var (
+	shuffle = flag.String("testkit.shuffle", shuffleOff, "order of the test methods and table cases: on, off or the seed to shuffle them with")
+
+	// randomSeed is the seed used when shuffling is on, it is generated once so all the suites report the same seed
+	randomSeed = sync.OnceValue(func() int64 { return time.Now().UnixNano() })
+)
+
+// shuffleSeed returns the seed to shuffle the tests with, false when shuffling is off
+func shuffleSeed() (int64, bool, error) {
+	switch value := strings.TrimSpace(*shuffle); value {
+	case "", shuffleOff:
+		return 0, false, nil
+	case shuffleOn:
+		return randomSeed(), true, nil
+	default:
+		seed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return 0, false, errors.Errorf("invalid value for -testkit.shuffle: %s, must be on, off or an integer seed", value)
+		}
+		return seed, true, nil
+	}
+}
+
+// shuffled returns a copy of the items shuffled with the seed and the name of the test, so the order of each test is
+// reproducible with the same seed regardless of the other tests that ran
+func shuffled[E any](seed int64, name string, items []E) []E {
+	hash := fnv.New64a()
+	_, _ = hash.Write([]byte(name))
+
+	//nolint:gosec // the order of the tests does not need a secure random source
+	rng := rand.New(rand.NewSource(seed ^ int64(hash.Sum64())))
+
+	result := slices.Clone(items)
+	rng.Shuffle(len(result), func(i, j int) {
+		result[i], result[j] = result[j], result[i]
+	})
+	return result
+}
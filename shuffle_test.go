@@ -0,0 +1,37 @@
+package testkit_test
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bdpiprava/testkit"
+)
+
+type ShuffleSuiteTest struct {
+	testkit.Suite
+	executed []string
+}
+
+func TestShuffleSuiteTest(t *testing.T) {
+	require.NoError(t, flag.Set("testkit.shuffle", "42"))
+	t.Cleanup(func() {
+		require.NoError(t, flag.Set("testkit.shuffle", "off"))
+	})
+
+	first := new(ShuffleSuiteTest)
+	testkit.Run(t, first)
+	second := new(ShuffleSuiteTest)
+	testkit.Run(t, second)
+
+	require.ElementsMatch(t, []string{"Test_A", "Test_B", "Test_C", "Test_D", "Test_E", "Test_F"}, first.executed)
+	require.Equal(t, first.executed, second.executed, "the same seed must reproduce the same order")
+}
+
+func (s *ShuffleSuiteTest) Test_A() { s.executed = append(s.executed, "Test_A") }
+func (s *ShuffleSuiteTest) Test_B() { s.executed = append(s.executed, "Test_B") }
+func (s *ShuffleSuiteTest) Test_C() { s.executed = append(s.executed, "Test_C") }
+func (s *ShuffleSuiteTest) Test_D() { s.executed = append(s.executed, "Test_D") }
+func (s *ShuffleSuiteTest) Test_E() { s.executed = append(s.executed, "Test_E") }
+func (s *ShuffleSuiteTest) Test_F() { s.executed = append(s.executed, "Test_F") }
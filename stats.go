@@ -0,0 +1,148 @@
+package testkit
+
+import (
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// SuiteInformation is the timing and status of the tests run by the suite, it is passed to HandleStats
+type SuiteInformation struct {
+	Start     time.Time
+	End       time.Time
+	TestStats map[string]*TestInformation // TestStats by test name relative to the suite e.g. TestMethod/SubTest
+	Resources []TestResource              // Resources provisioned for the whole suite e.g. in SetupSuite
+
+	mu   sync.Mutex
+	name string // name of the test running the suite
+}
+
+// TestInformation is the timing and status of a test method or a subtest
+type TestInformation struct {
+	TestName  string // TestName is the name of the test relative to the suite e.g. TestMethod/SubTest
+	Start     time.Time
+	End       time.Time
+	Passed    bool
+	Skipped   bool
+	Failures  []string       // Failures reported by the assertions of the test
+	Panic     string         // Panic value and stack trace when the test panicked
+	Resources []TestResource // Resources provisioned by the test
+	Attempts  int            // Attempts made to run the test method when it is retried on failure
+}
+
+// TestResource is a resource provisioned by a test e.g. a database or a kafka cluster
+type TestResource struct {
+	Kind string // Kind of the resource e.g. postgres.database
+	Name string // Name identifying the resource e.g. the database name or the bootstrap servers
+}
+
+// statsRecorder is implemented by the suites recording the stats of the subtests
+type statsRecorder interface {
+	setStats(stats *SuiteInformation)
+	suiteStats() *SuiteInformation
+}
+
+// newSuiteInformation returns the stats of the suite run by the test with the given name
+func newSuiteInformation(name string) *SuiteInformation {
+	return &SuiteInformation{
+		Start:     time.Now(),
+		TestStats: make(map[string]*TestInformation),
+		name:      name,
+	}
+}
+
+// Duration returns the time taken by the test
+func (i *TestInformation) Duration() time.Duration {
+	return i.End.Sub(i.Start)
+}
+
+// Flaky returns true if the test method passed only after being retried
+func (i *TestInformation) Flaky() bool {
+	return i.Passed && i.Attempts > 1
+}
+
+// start records the start of the test and returns a function which records its completion
+func (s *SuiteInformation) start(t *testing.T) func() {
+	if s == nil {
+		return func() {}
+	}
+
+	info := &TestInformation{
+		TestName: strings.TrimPrefix(t.Name(), s.name+"/"),
+		Start:    time.Now(),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.TestStats[info.TestName] = info
+
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		info.End = time.Now()
+		info.Passed = !t.Failed()
+		info.Skipped = t.Skipped()
+	}
+}
+
+// recordFailure records the failure message reported by the test
+func (s *SuiteInformation) recordFailure(t *testing.T, message string) {
+	s.update(t, func(info *TestInformation) {
+		info.Failures = append(info.Failures, message)
+	})
+}
+
+// recordPanic records the recovered panic value along with the stack trace
+func (s *SuiteInformation) recordPanic(t *testing.T, r any) {
+	if r == nil {
+		return
+	}
+
+	stack := string(debug.Stack())
+	s.update(t, func(info *TestInformation) {
+		info.Panic = fmt.Sprintf("%v\n%s", r, stack)
+	})
+}
+
+// recordResource records a resource provisioned by the test, resources provisioned outside a test belong to the suite
+func (s *SuiteInformation) recordResource(t *testing.T, kind, name string) {
+	if s == nil {
+		return
+	}
+
+	resource := TestResource{Kind: kind, Name: name}
+	if t.Name() == s.name {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.Resources = append(s.Resources, resource)
+		return
+	}
+
+	s.update(t, func(info *TestInformation) {
+		info.Resources = append(info.Resources, resource)
+	})
+}
+
+// update applies the change to the stats of the test if they are recorded
+func (s *SuiteInformation) update(t *testing.T, change func(info *TestInformation)) {
+	if s == nil {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if info, ok := s.TestStats[strings.TrimPrefix(t.Name(), s.name+"/")]; ok {
+		change(info)
+	}
+}
+
+// statsOf returns the stats recorded by the suite, nil if the suite does not record the stats
+func statsOf(suite TestingSuite) *SuiteInformation {
+	if recorder, ok := suite.(statsRecorder); ok {
+		return recorder.suiteStats()
+	}
+	return nil
+}
@@ -3,18 +3,17 @@ package testkit
 import (
 	"context"
 	"flag"
+	"fmt"
 	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/confluentinc/confluent-kafka-go/v2/kafka"
-	"github.com/elastic/go-elasticsearch/v7"
-	"github.com/opensearch-project/opensearch-go/v2"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
-	"github.com/wiremock/go-wiremock"
 
 	"github.com/bdpiprava/testkit/internal"
 	"github.com/bdpiprava/testkit/suite"
@@ -23,27 +22,33 @@ import (
 var (
 	allTestsFilter = func(_, _ string) (bool, error) { return true, nil }
 	matchMethod    = flag.String("testkit.m", "", "regular expression to select tests of the testify suite to run")
-	esClient       *elasticsearch.Client
-	osClient       *opensearch.Client
-	wiremockClient *wiremock.Client
-	suiteConfig    *internal.SuiteConfig
-)
+	fileConfig     *internal.SuiteConfig
 
-const defaultWiremockAddress = "http://localhost:8080"
+	// fileSections are the sections of the config file by key, passed to the providers
+	fileSections map[string]any
+)
 
 type Suite struct {
 	suite.Suite
 	*assert.Assertions
 
-	mu  sync.RWMutex
-	t   *testing.T
-	ctx context.Context
-	r   *require.Assertions
-	l   logrus.FieldLogger
+	mu      sync.RWMutex
+	t       *testing.T
+	ctx     context.Context
+	r       *require.Assertions
+	l       logrus.FieldLogger
+	stats   *SuiteInformation
+	attempt *attempt
+	suiteT  *testing.T // suiteT owns the resources of ScopeSuite
+	testT   *testing.T // testT owns the resources of ScopeTest
+
+	config    *internal.SuiteConfig // config is the config of the suite, the config file unless overridden
+	override  *internal.SuiteConfig // override is the config given to RunWithConfig
+	providers map[string]Provider   // providers are the providers initialised with the config of the suite
 
-	kafkaServers   map[string]*kafka.MockCluster
-	kafkaConsumers []*kafka.Consumer
-	postgresDBs    map[string]psqlDataHolder
+	resources      map[string][]acquiredResource
+	kafkaConsumers map[string][]*kafka.Consumer
+	kafkaProducers map[string]*testProducers
 
 	// Parent suite to have access to the implemented methods of parent struct
 	s TestingSuite
@@ -61,8 +66,39 @@ func (s *Suite) SetT(t *testing.T) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.t = t
-	s.Assertions = assert.New(t)
-	s.r = require.New(t)
+	reporter := &reportingT{T: t, stats: s.stats, attempt: s.attempt}
+	s.Assertions = assert.New(reporter)
+	s.r = require.New(reporter)
+}
+
+// reportingT reports the failures of the assertions to the test and records them in the stats of the suite
+type reportingT struct {
+	*testing.T
+	stats   *SuiteInformation
+	attempt *attempt
+}
+
+// Errorf records the failure and reports it to the test, the failure is only logged when the test method will be retried
+func (r *reportingT) Errorf(format string, args ...any) {
+	r.T.Helper()
+	message := fmt.Sprintf(format, args...)
+	r.stats.recordFailure(r.T, message)
+	if r.attempt.soft() {
+		r.attempt.fail()
+		r.T.Log(message)
+		return
+	}
+	r.T.Errorf(format, args...)
+}
+
+// FailNow stops the test, the attempts which will be retried are reported as skipped instead of failed
+func (r *reportingT) FailNow() {
+	r.T.Helper()
+	if r.attempt.soft() {
+		r.attempt.fail()
+		r.T.SkipNow()
+	}
+	r.T.FailNow()
 }
 
 // DoOnce setup the suite
@@ -77,12 +113,14 @@ func (s *Suite) SetS(suite TestingSuite) {
 
 // TearDownSuite perform the cleanup of the database
 func (s *Suite) TearDownSuite() {
-	defer s.cleanDatabase()
-	defer s.cleanKafkaResources()
+	defer s.releaseResources("")
+	defer s.cleanKafkaResources("")
 }
 
-// GetContext returns the context created for the current test, if not exists then creates a new context and returns
+// GetContext returns the context created for the current test, it is cancelled when the test completes
 func (s *Suite) GetContext() context.Context {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	return s.ctx
 }
 
@@ -104,137 +142,176 @@ func (s *Suite) Run(name string, subtest func()) bool {
 	oldT := s.T()
 
 	return oldT.Run(name, func(t *testing.T) {
+		defer s.startTestContext(t, 0)()
+
 		s.SetT(t)
 		defer s.SetT(oldT)
 
-		defer recoverAndFailOnPanic(t)
-
-		if setupSubTest, ok := s.s.(SetupTest); ok {
-			setupSubTest.SetupTest()
-		}
-
-		if tearDownSubTest, ok := s.s.(TearDownTest); ok {
-			defer tearDownSubTest.TearDownTest()
-		}
-
-		subtest()
+		runSubTest(t, s.s, subtest)
 	})
 }
 
-// initializeSuite initialize the suite
-func (s *Suite) initializeSuite(_ *testing.T) error {
-	s.ctx = context.Background()
-	s.kafkaServers = make(map[string]*kafka.MockCluster)
-	s.postgresDBs = make(map[string]psqlDataHolder)
-	s.kafkaConsumers = make([]*kafka.Consumer, 0)
+// setStats sets the stats recorded for the tests of the suite
+func (s *Suite) setStats(stats *SuiteInformation) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats = stats
+}
 
-	logger := logrus.New()
-	config, err := getConfig()
-	if err != nil {
-		return err
-	}
-	level, err := logrus.ParseLevel(config.LogLevel)
-	if err != nil {
-		logger.WithError(err).Warn("failed to parse log level, initializing with default")
-		level = logrus.InfoLevel
-	}
-	logger.SetLevel(level)
-	s.l = logrus.NewEntry(logger)
+// suiteStats returns the stats recorded for the tests of the suite
+func (s *Suite) suiteStats() *SuiteInformation {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.stats
+}
 
-	db, err := internal.InitialiseDatabase(*config, s.l)
-	if err != nil && !errors.Is(err, internal.ErrMissingGoMigrateConfig) {
-		return err
-	}
-	defer closeSilently(db)
+// recordResource records a resource provisioned by the current test in the stats of the suite
+func (s *Suite) recordResource(kind, name string) {
+	s.suiteStats().recordResource(s.T(), kind, name)
+}
 
-	if wiremockClient == nil {
-		wiremockClient = wiremock.NewClient(config.APIMockConfig.Address)
-	}
+// startAttempt starts an attempt to run a test method which is retried on failure, the resources provisioned
+// during the attempt are released once it completes so the next attempt starts afresh
+func (s *Suite) startAttempt(t *testing.T, final bool) *attempt {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempt = &attempt{final: final}
 
-	if err = initialiseElasticSearch(config.ElasticSearch, s.l); err != nil {
-		return err
-	}
+	t.Cleanup(func() {
+		s.cleanKafkaResources(t.Name())
 
-	if err = initialiseOpenSearch(config.OpenSearch, s.l); err != nil {
-		return err
-	}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.attempt = nil
+	})
+	return s.attempt
+}
 
-	return nil
+// currentAttempt returns the current attempt, nil if the running test method is not retried
+func (s *Suite) currentAttempt() *attempt {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.attempt
 }
 
-// initialiseElasticSearch initializes the elastic search client
-func initialiseElasticSearch(config *internal.ElasticSearchConfig, log logrus.FieldLogger) (err error) {
-	if esClient != nil {
-		return nil
-	}
+// testContextStarter is implemented by the suites which derive a context for each test
+type testContextStarter interface {
+	startTestContext(t *testing.T, timeout time.Duration) func()
+}
 
-	if config == nil {
-		return nil
+// startTestContext derives the context of the test from the current context and returns a function which cancels it
+// and restores the previous one. When the timeout is positive the test is marked as failed once it elapses and the
+// context is cancelled, the test goroutine is not interrupted so only the code watching GetContext returns early
+func (s *Suite) startTestContext(t *testing.T, timeout time.Duration) func() {
+	parent := s.GetContext()
+	var ctx context.Context
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeoutCause(parent, timeout, fmt.Errorf("test %s exceeded the timeout of %s", t.Name(), timeout))
+	} else {
+		ctx, cancel = context.WithCancel(parent)
 	}
 
-	esClient, err = elasticsearch.NewClient(elasticsearch.Config{
-		Addresses: strings.Split(config.Addresses, ","),
-		Username:  config.Username,
-		Password:  config.Password,
+	var done bool
+	var doneMu sync.Mutex
+	stop := context.AfterFunc(ctx, func() {
+		doneMu.Lock()
+		defer doneMu.Unlock()
+		if !done && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			reporter := &reportingT{T: t, stats: s.suiteStats(), attempt: s.currentAttempt()}
+			reporter.Errorf("testkit: %v", context.Cause(ctx))
+		}
 	})
 
-	if err != nil {
-		return err
-	}
+	s.mu.Lock()
+	s.ctx = ctx
+	s.mu.Unlock()
 
-	res, err := esClient.Ping()
-	if err != nil {
-		return err
-	}
+	return func() {
+		doneMu.Lock()
+		done = true
+		doneMu.Unlock()
 
-	log.Infof("Connected to elastic search cluster: %v", res.String())
-	return nil
-}
+		stop()
+		cancel()
 
-// initialiseOpenSearch initializes the open search client
-func initialiseOpenSearch(config *internal.ElasticSearchConfig, log logrus.FieldLogger) (err error) {
-	if osClient != nil {
-		return nil
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.ctx = parent
 	}
+}
 
-	if config == nil {
-		return nil
-	}
+// forkable is implemented by the suites which can be detached from the shared state to run a test in parallel
+type forkable interface {
+	fork(t *testing.T)
+}
 
-	osClient, err = opensearch.NewClient(opensearch.Config{
-		Addresses: strings.Split(config.Addresses, ","),
-		Username:  config.Username,
-		Password:  config.Password,
+// fork detaches the copy of the suite from the state shared with the parent suite, resources created
+// before the fork stay visible to the lookups while the ones created by the test are released when it completes
+func (s *Suite) fork(t *testing.T) {
+	s.mu = sync.RWMutex{}
+	s.resources = s.forkResources()
+	s.kafkaConsumers = make(map[string][]*kafka.Consumer)
+	s.kafkaProducers = make(map[string]*testProducers)
+	s.l = s.l.WithField("test", t.Name())
+
+	t.Cleanup(func() {
+		s.cleanKafkaResources(t.Name())
 	})
+}
+
+// ownedBy returns true if the resource registered under the key belongs to the test or its subtests,
+// an empty test name owns all the resources
+func ownedBy(key, testName string) bool {
+	return testName == "" || key == testName || strings.HasPrefix(key, testName+"/")
+}
+
+// initializeSuite initialize the suite
+func (s *Suite) initializeSuite(_ *testing.T) error {
+	s.ctx = context.Background()
+	s.resources = make(map[string][]acquiredResource)
+	s.kafkaConsumers = make(map[string][]*kafka.Consumer)
+	s.kafkaProducers = make(map[string]*testProducers)
 
+	logger := logrus.New()
+	config, sections, err := s.resolveConfig()
 	if err != nil {
 		return err
 	}
+	s.config = config
 
-	res, err := osClient.Ping()
+	level, err := logrus.ParseLevel(config.LogLevel)
 	if err != nil {
+		logger.WithError(err).Warn("failed to parse log level, initializing with default")
+		level = logrus.InfoLevel
+	}
+	logger.SetLevel(level)
+	s.l = logrus.NewEntry(logger)
+
+	if s.providers, err = initialiseProviders(sections, config.Readiness, s.l); err != nil {
 		return err
 	}
 
-	log.Infof("Connected to open search cluster: %v", res.String())
+	db, err := internal.InitialiseDatabase(*config, s.l)
+	if err != nil && !errors.Is(err, internal.ErrMissingGoMigrateConfig) {
+		return err
+	}
+	closeSilently(db)
 	return nil
 }
 
-// getConfig reads the suite configuration from the file
-func getConfig() (*internal.SuiteConfig, error) {
-	if suiteConfig != nil {
-		return suiteConfig, nil
+// loadFileConfig reads and validates the configuration from the file and returns it with the sections of the file
+func loadFileConfig() (*internal.SuiteConfig, map[string]any, error) {
+	if fileConfig != nil {
+		return fileConfig, fileSections, nil
 	}
 
-	cfg, err := internal.ReadConfigAs[internal.SuiteConfig]()
+	cfg, sections, err := internal.LoadSuiteConfig(providerNames())
 	if err != nil {
-		return nil, err
-	}
-
-	if cfg.APIMockConfig == nil {
-		cfg.APIMockConfig = &internal.APIMockConfig{Address: defaultWiremockAddress}
+		return nil, nil, err
 	}
 
-	suiteConfig = &cfg
-	return suiteConfig, nil
+	fileConfig = &cfg
+	fileSections = sections
+	return fileConfig, fileSections, nil
 }
@@ -0,0 +1,81 @@
+package testkit
+
+import "testing"
+
+// Case is a single case of a table driven test
+type Case[T any] struct {
+	Name     string // Name of the subtest running the case
+	Input    T      // Input passed to the case function
+	Skip     bool   // Skip reports the case as skipped without running it
+	Only     bool   // Only focuses the table on the cases marked as only, the others are skipped
+	Parallel bool   // Parallel runs the case concurrently against an isolated copy of the suite
+}
+
+// Table is the list of cases of a table driven test
+type Table[T any] []Case[T]
+
+// RunCases runs each case of the table as a subtest of the current test through Suite.Run, so SetupSubTest and
+// TearDownSubTest run around every case. The case function receives the suite to make assertions with, for parallel cases it
+// is an isolated copy of the suite. Like parallel subtests, the parallel cases run concurrently once the current test
+// function returned, RunCases returns before they run. The input of a failed or panicking case is logged and the
+// cases are shuffled with the tests when the -testkit.shuffle flag is set.
+//
+//	testkit.RunCases(s, testkit.Table[string]{
+//		{Name: "lower", Input: "abc"},
+//		{Name: "upper", Input: "ABC", Parallel: true},
+//	}, func(s *ExampleTestSuite, input string) {
+//		s.NotEmpty(input)
+//	})
+func RunCases[S SubTestRunner, T any](s S, cases Table[T], fn func(s S, input T)) {
+	focused := cases.hasOnly()
+	if seed, ok, _ := shuffleSeed(); ok {
+		cases = shuffled(seed, s.T().Name(), cases)
+	}
+
+	parentT := s.T()
+	for _, tc := range cases {
+		if tc.Skip || (focused && !tc.Only) {
+			parentT.Run(tc.Name, func(t *testing.T) {
+				t.Skip("skipped by the table")
+			})
+			continue
+		}
+
+		if tc.Parallel {
+			parentT.Run(tc.Name, func(t *testing.T) {
+				isolated := isolate(t, cloneSuite(s)).(S)
+				t.Parallel()
+				defer startTestContext(t, isolated, 0)()
+				logCaseOnFailure(t, tc)
+				runSubTest(t, isolated, func() {
+					fn(isolated, tc.Input)
+				})
+			})
+			continue
+		}
+
+		s.Run(tc.Name, func() {
+			logCaseOnFailure(s.T(), tc)
+			fn(s, tc.Input)
+		})
+	}
+}
+
+// hasOnly returns true if any case of the table is marked as only
+func (t Table[T]) hasOnly() bool {
+	for _, tc := range t {
+		if tc.Only {
+			return true
+		}
+	}
+	return false
+}
+
+// logCaseOnFailure logs the input of the case once the subtest completed when it failed, including when it panicked
+func logCaseOnFailure[T any](t *testing.T, tc Case[T]) {
+	t.Cleanup(func() {
+		if t.Failed() {
+			t.Logf("case %q failed with input: %+v", tc.Name, tc.Input)
+		}
+	})
+}
@@ -0,0 +1,83 @@
+package testkit_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/bdpiprava/testkit"
+)
+
+type TableTestSuite struct {
+	testkit.Suite
+	mu       sync.Mutex
+	executed []string
+}
+
+func TestTableTestSuite(t *testing.T) {
+	testkit.Run(t, new(TableTestSuite))
+}
+
+func (s *TableTestSuite) SetupTest() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.executed = make([]string, 0)
+}
+
+func (s *TableTestSuite) Test_RunCases_ShouldRunCasesAsSubtests() {
+	var names []string
+	testkit.RunCases(s, testkit.Table[int]{
+		{Name: "first", Input: 1},
+		{Name: "second", Input: 2},
+	}, func(s *TableTestSuite, input int) {
+		names = append(names, s.T().Name())
+		s.Positive(input)
+	})
+
+	s.Equal([]string{
+		"TestTableTestSuite/Test_RunCases_ShouldRunCasesAsSubtests/first",
+		"TestTableTestSuite/Test_RunCases_ShouldRunCasesAsSubtests/second",
+	}, names)
+}
+
+func (s *TableTestSuite) Test_RunCases_ShouldSkipCases() {
+	var inputs []string
+	testkit.RunCases(s, testkit.Table[string]{
+		{Name: "skipped", Input: "skipped", Skip: true},
+		{Name: "executed", Input: "executed"},
+	}, func(_ *TableTestSuite, input string) {
+		inputs = append(inputs, input)
+	})
+
+	s.Equal([]string{"executed"}, inputs)
+}
+
+func (s *TableTestSuite) Test_RunCases_ShouldOnlyRunFocusedCases() {
+	var inputs []string
+	testkit.RunCases(s, testkit.Table[string]{
+		{Name: "not-focused", Input: "not-focused"},
+		{Name: "focused", Input: "focused", Only: true},
+	}, func(_ *TableTestSuite, input string) {
+		inputs = append(inputs, input)
+	})
+
+	s.Equal([]string{"focused"}, inputs)
+}
+
+func (s *TableTestSuite) Test_RunCases_ShouldRunParallelCasesAgainstIsolatedSuite() {
+	parent := s.T().Name()
+	// the parallel cases run once the function of the subtest returned, before the subtest completes
+	s.Run("table", func() {
+		testkit.RunCases(s, testkit.Table[string]{
+			{Name: "first", Input: "first", Parallel: true},
+			{Name: "second", Input: "second", Parallel: true},
+		}, func(isolated *TableTestSuite, input string) {
+			isolated.Equal(parent+"/table/"+input, isolated.T().Name())
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			s.executed = append(s.executed, input)
+		})
+	})
+
+	s.Equal(parent, s.T().Name())
+	s.ElementsMatch([]string{"first", "second"}, s.executed)
+}
@@ -0,0 +1,94 @@
+package testkit
+
+import (
+	"flag"
+	"slices"
+	"strings"
+
+	"github.com/bdpiprava/testkit/internal"
+)
+
+// The tags of the tests which require a backend, the tests are skipped when the backend is not configured
+const (
+	TagRequiresPostgres   = "requires-postgres"
+	TagRequiresES         = "requires-es"
+	TagRequiresOpenSearch = "requires-opensearch"
+	TagRequiresAPIMock    = "requires-api-mock"
+)
+
+var matchTags = flag.String("testkit.tags", "", "comma separated list of tags to select tests, tags prefixed with ! exclude the tests e.g. kafka,!slow")
+
+// tagFilter selects the tests by their tags
+type tagFilter struct {
+	include []string
+	exclude []string
+}
+
+// parseTagFilter parses the comma separated list of tags, the tags prefixed with ! are excluded
+func parseTagFilter(value string) tagFilter {
+	var filter tagFilter
+	for _, tag := range strings.Split(value, ",") {
+		tag = strings.TrimSpace(tag)
+		if excluded, ok := strings.CutPrefix(tag, "!"); ok {
+			if excluded = strings.TrimSpace(excluded); excluded != "" {
+				filter.exclude = append(filter.exclude, excluded)
+			}
+			continue
+		}
+
+		if tag != "" {
+			filter.include = append(filter.include, tag)
+		}
+	}
+	return filter
+}
+
+// matches returns true if the test has none of the excluded tags and, when tags are included, at least one of them
+func (f tagFilter) matches(tags []string) bool {
+	for _, tag := range f.exclude {
+		if slices.Contains(tags, tag) {
+			return false
+		}
+	}
+
+	if len(f.include) == 0 {
+		return true
+	}
+
+	for _, tag := range f.include {
+		if slices.Contains(tags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// tagsOf returns the tags of the test method
+func tagsOf(suite TestingSuite, methodName string) []string {
+	if tagged, ok := suite.(Tags); ok {
+		return tagged.Tags()[methodName]
+	}
+	return nil
+}
+
+// missingBackend returns the name of the first backend required by the tags which is not configured, empty if all are
+func missingBackend(tags []string, config *internal.SuiteConfig) string {
+	for _, tag := range tags {
+		switch {
+		case tag == TagRequiresPostgres && !isPostgresConfigured(config):
+			return "postgres"
+		case tag == TagRequiresES && (config == nil || config.ElasticSearch == nil):
+			return "elasticsearch"
+		case tag == TagRequiresOpenSearch && (config == nil || config.OpenSearch == nil):
+			return "opensearch"
+		case tag == TagRequiresAPIMock && (config == nil || config.APIMockConfig == nil):
+			return "api-mock"
+		}
+	}
+	return ""
+}
+
+// isPostgresConfigured returns true if the postgres connection is configured
+func isPostgresConfigured(config *internal.SuiteConfig) bool {
+	return config != nil && strings.TrimSpace(config.PostgresConfig.Host) != ""
+}
@@ -0,0 +1,51 @@
+package testkit_test
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/bdpiprava/testkit"
+)
+
+type TagsSuiteTest struct {
+	testkit.Suite
+	executed []string
+}
+
+func TestTagsSuiteTest(t *testing.T) {
+	require.NoError(t, flag.Set("testkit.tags", "fast,!slow"))
+	t.Cleanup(func() {
+		require.NoError(t, flag.Set("testkit.tags", ""))
+	})
+
+	s := new(TagsSuiteTest)
+	testkit.Run(t, s)
+
+	require.Equal(t, []string{"Test_Fast"}, s.executed)
+}
+
+func (s *TagsSuiteTest) Tags() map[string][]string {
+	return map[string][]string{
+		"Test_Fast":     {"fast"},
+		"Test_FastSlow": {"fast", "slow"},
+		"Test_Slow":     {"slow"},
+	}
+}
+
+func (s *TagsSuiteTest) Test_Fast() {
+	s.executed = append(s.executed, "Test_Fast")
+}
+
+func (s *TagsSuiteTest) Test_FastSlow() {
+	s.executed = append(s.executed, "Test_FastSlow")
+}
+
+func (s *TagsSuiteTest) Test_Slow() {
+	s.executed = append(s.executed, "Test_Slow")
+}
+
+func (s *TagsSuiteTest) Test_Untagged() {
+	s.executed = append(s.executed, "Test_Untagged")
+}
@@ -25,6 +25,9 @@ func execute(client *Client, request *Request, respType any) (*Response, error)
 	log.Default().Println("Executing request:", req.Method, req.URL.String())
 	resp, err := client.client.Do(req)
 	if err != nil {
+		if cause := context.Cause(req.Context()); cause != nil {
+			return nil, errors.Wrapf(err, "failed to execute request: %v", cause)
+		}
 		return nil, errors.Wrap(err, "failed to execute request")
 	}
 
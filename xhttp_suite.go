@@ -0,0 +1,12 @@
+package testkit
+
+import (
+	"github.com/bdpiprava/testkit/xhttp"
+)
+
+// NewHTTPRequest returns a new xhttp request bound to the context of the current test,
+// the request is cancelled when the test completes or times out
+func (s *Suite) NewHTTPRequest(method string, opts ...xhttp.RequestOption) *xhttp.Request {
+	opts = append([]xhttp.RequestOption{xhttp.WithContext(s.GetContext())}, opts...)
+	return xhttp.NewRequest(method, opts...)
+}